@@ -0,0 +1,181 @@
+// Package audit compares the scanned collection with the game database. It
+// reports the games we have, the ones missing, the bad dumps, the files that
+// were not recognized and the duplicates, per system.
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/libretro/ludo/dat"
+	"github.com/libretro/ludo/scanner"
+	"github.com/libretro/ludo/utils"
+)
+
+// Status is the state of a game or a file in the audit
+type Status string
+
+// The possible statuses
+const (
+	Have         Status = "have"
+	Missing      Status = "missing"
+	BadDump      Status = "baddump"
+	Unrecognized Status = "unrecognized"
+	Duplicate    Status = "duplicate"
+)
+
+// Row is a line of the audit, about a game of the database or a file
+type Row struct {
+	Status Status `json:"status"`
+	Game   string `json:"game,omitempty"` // Name of the game in the database
+	Path   string `json:"path,omitempty"` // Empty if the game is missing
+}
+
+// System is the audit of the games of a system. The files that were not
+// recognized don't belong to a system, they are grouped in a System without
+// name.
+type System struct {
+	Name  string `json:"name"`
+	Total int    `json:"total"` // Number of games in the database
+	Rows  []Row  `json:"rows"`
+}
+
+// Count returns the number of rows having a status
+func (s System) Count(status Status) int {
+	n := 0
+	for _, r := range s.Rows {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Report is the audit of the collection
+type Report struct {
+	Systems []System `json:"systems"`
+}
+
+// isBadDump tells if a game of the database is a known bad dump
+func isBadDump(game dat.Game) bool {
+	return game.ROMs[0].Status == "baddump" || strings.Contains(game.Name, "[b]")
+}
+
+// Audit compares the files of the scan index with the database. Only the
+// systems having at least one file in the collection are reported.
+func Audit(db *dat.DB, idx scanner.Index) Report {
+	// The games of each system, by name
+	games := map[string]map[string]dat.Game{}
+	// The systems of the game names, to recognize renamed bad dumps
+	names := map[string]string{}
+	for system, d := range db.Dats {
+		games[system] = map[string]dat.Game{}
+		for _, game := range d.Games {
+			if len(game.ROMs) == 0 {
+				continue
+			}
+			games[system][game.Name] = game
+			names[game.Name] = system
+		}
+	}
+
+	owned := map[string]map[string][]string{} // system, game name, paths
+	rows := map[string][]Row{}                // bad dumps and unrecognized files
+	present := map[string]bool{}              // systems found in the collection
+	own := func(system, name, path string) {
+		if owned[system] == nil {
+			owned[system] = map[string][]string{}
+		}
+		owned[system][name] = append(owned[system][name], path)
+		present[system] = true
+	}
+
+	var paths []string
+	for path := range idx {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		e := idx[path]
+		if len(e.Games) == 0 {
+			// A file named like a game, but with a different checksum
+			name := utils.FileName(path)
+			if system, ok := names[name]; ok {
+				rows[system] = append(rows[system], Row{Status: BadDump, Game: name, Path: path})
+				present[system] = true
+			} else {
+				rows[""] = append(rows[""], Row{Status: Unrecognized, Path: path})
+			}
+			continue
+		}
+		for _, g := range e.Games {
+			if game, ok := games[g.System][g.Name]; ok && isBadDump(game) {
+				rows[g.System] = append(rows[g.System], Row{Status: BadDump, Game: g.Name, Path: path})
+				present[g.System] = true
+				continue
+			}
+			own(g.System, g.Name, path)
+		}
+	}
+
+	var report Report
+	var systems []string
+	for system := range present {
+		systems = append(systems, system)
+	}
+	sort.Strings(systems)
+
+	for _, system := range systems {
+		s := System{Name: system, Total: len(games[system])}
+		var sorted []string
+		for name := range games[system] {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			if isBadDump(games[system][name]) {
+				continue
+			}
+			paths := owned[system][name]
+			if len(paths) == 0 {
+				s.Rows = append(s.Rows, Row{Status: Missing, Game: name})
+				continue
+			}
+			s.Rows = append(s.Rows, Row{Status: Have, Game: name, Path: paths[0]})
+			for _, path := range paths[1:] {
+				s.Rows = append(s.Rows, Row{Status: Duplicate, Game: name, Path: path})
+			}
+		}
+		s.Rows = append(s.Rows, rows[system]...)
+		report.Systems = append(report.Systems, s)
+	}
+
+	if len(rows[""]) > 0 {
+		report.Systems = append(report.Systems, System{Rows: rows[""]})
+	}
+	return report
+}
+
+// WriteCSV writes the report as CSV, one row per game or file
+func (r Report) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"system", "status", "game", "path"})
+	for _, s := range r.Systems {
+		for _, row := range s.Rows {
+			c.Write([]string{s.Name, string(row.Status), row.Game, row.Path})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
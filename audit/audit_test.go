@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/dat"
+	"github.com/libretro/ludo/scanner"
+)
+
+func testDB() dat.DB {
+	return dat.NewDB(map[string]dat.Dat{
+		"Nintendo - SNES": {Games: []dat.Game{
+			{Name: "Game (USA)", ROMs: []dat.ROM{{CRC: 1}}},
+			{Name: "Game (Europe)", ROMs: []dat.ROM{{CRC: 2}}},
+			{Name: "Game (Japan) (Rev 1)", ROMs: []dat.ROM{{CRC: 3}}},
+			{Name: "Other (Japan)", ROMs: []dat.ROM{{CRC: 4}}},
+			{Name: "Other (Japan) (Rev 1)", ROMs: []dat.ROM{{CRC: 5}}},
+			{Name: "Other (USA) (Beta)", ROMs: []dat.ROM{{CRC: 6}}},
+			{Name: "Broken (USA)", ROMs: []dat.ROM{{CRC: 7, Status: "baddump"}}},
+		}},
+		"Sega - Saturn": {Games: []dat.Game{
+			{Name: "Disc (Japan)", ROMs: []dat.ROM{{CRC: 8}}},
+		}},
+	})
+}
+
+func testIndex() scanner.Index {
+	snes := func(name string) []scanner.IndexGame {
+		return []scanner.IndexGame{{System: "Nintendo - SNES", Name: name}}
+	}
+	return scanner.Index{
+		"/roms/game.sfc":       {Games: snes("Game (Europe)")},
+		"/roms/copy/game.sfc":  {Games: snes("Game (Europe)")},
+		"/roms/other.sfc":      {Games: snes("Other (Japan)")},
+		"/roms/broken.sfc":     {Games: snes("Broken (USA)")},
+		"/roms/Game (USA).sfc": {},
+		"/roms/unknown.sfc":    {},
+	}
+}
+
+func TestAudit(t *testing.T) {
+	db := testDB()
+	report := Audit(&db, testIndex())
+
+	want := Report{Systems: []System{
+		{Name: "Nintendo - SNES", Total: 7, Rows: []Row{
+			{Status: Have, Game: "Game (Europe)", Path: "/roms/copy/game.sfc"},
+			{Status: Duplicate, Game: "Game (Europe)", Path: "/roms/game.sfc"},
+			{Status: Missing, Game: "Game (Japan) (Rev 1)"},
+			{Status: Missing, Game: "Game (USA)"},
+			{Status: Have, Game: "Other (Japan)", Path: "/roms/other.sfc"},
+			{Status: Missing, Game: "Other (Japan) (Rev 1)"},
+			{Status: Missing, Game: "Other (USA) (Beta)"},
+			{Status: BadDump, Game: "Game (USA)", Path: "/roms/Game (USA).sfc"},
+			{Status: BadDump, Game: "Broken (USA)", Path: "/roms/broken.sfc"},
+		}},
+		{Rows: []Row{{Status: Unrecognized, Path: "/roms/unknown.sfc"}}},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Audit() = %+v, want %+v", report, want)
+	}
+	if got := report.Systems[0].Count(Missing); got != 4 {
+		t.Errorf("Count() = %v, want %v", got, 4)
+	}
+
+	var buf bytes.Buffer
+	if err := (Report{Systems: report.Systems[1:]}).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "system,status,game,path\n,unrecognized,,/roms/unknown.sfc\n"; got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestOneGameOneROM(t *testing.T) {
+	db := testDB()
+	report := Audit(&db, testIndex())
+
+	t.Run("Picks by region priority", func(t *testing.T) {
+		picks := OneGameOneROM(&db, report, []string{"USA", "Europe", "Japan"})
+		want := []Pick{
+			{System: "Nintendo - SNES", Title: "Game", Game: "Game (USA)"},
+			{System: "Nintendo - SNES", Title: "Other", Game: "Other (Japan) (Rev 1)"},
+		}
+		if !reflect.DeepEqual(picks, want) {
+			t.Errorf("OneGameOneROM() = %+v, want %+v", picks, want)
+		}
+	})
+
+	t.Run("Prefers the games we have", func(t *testing.T) {
+		picks := OneGameOneROM(&db, report, []string{"Europe"})
+		if len(picks) != 2 || picks[0].Game != "Game (Europe)" || picks[0].Path != "/roms/copy/game.sfc" {
+			t.Errorf("OneGameOneROM() = %+v", picks)
+		}
+	})
+}
+
+func Test_parseVersion(t *testing.T) {
+	regions := []string{"USA", "Europe"}
+	tests := []struct {
+		name string
+		want version
+	}{
+		{"Game (USA, Europe)", version{region: 0}},
+		{"Game (Europe) (Rev A)", version{region: 1, revision: 1}},
+		{"Game (Japan) (v1.1)", version{region: 2, revision: 1.1}},
+		{"Game (Europe) (Proto 2)", version{region: 1, unreleased: true}},
+	}
+	for _, tt := range tests {
+		if got := parseVersion(tt.name, regions); got != tt.want {
+			t.Errorf("parseVersion(%v) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/dat"
+)
+
+// Pick is the version of a title kept in the "one game, one ROM" view
+type Pick struct {
+	System string `json:"system"`
+	Title  string `json:"title"`
+	Game   string `json:"game"`
+	Path   string `json:"path,omitempty"` // Empty if the game is missing
+}
+
+// tagGroup matches the (...) and [...] groups of the game names, like in
+// "Game (USA, Europe) (Rev 1)"
+var tagGroup = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+
+// title returns the name of a game without its tags
+func title(name string) string {
+	return strings.TrimSpace(tagGroup.ReplaceAllString(name, ""))
+}
+
+// tags returns the tags of a game name, "Game (USA, Europe) (Rev 1)" has the
+// tags USA, Europe and Rev 1
+func tags(name string) []string {
+	var out []string
+	for _, group := range tagGroup.FindAllString(name, -1) {
+		group = strings.Trim(strings.TrimSpace(group), "()[]")
+		for _, tag := range strings.Split(group, ",") {
+			out = append(out, strings.TrimSpace(tag))
+		}
+	}
+	return out
+}
+
+// unreleased are the tags of the versions only picked when there is nothing
+// else
+var unreleased = []string{"Beta", "Proto", "Demo", "Sample", "Pirate", "Unl", "Kiosk"}
+
+// version describes a game name to compare it to the other versions of the
+// same title
+type version struct {
+	unreleased bool
+	region     int // Position of the region in the priority list
+	revision   float64
+}
+
+func parseVersion(name string, regions []string) version {
+	v := version{region: len(regions)}
+	for _, tag := range tags(name) {
+		for _, u := range unreleased {
+			if strings.HasPrefix(tag, u) {
+				v.unreleased = true
+			}
+		}
+		for i, region := range regions {
+			if strings.EqualFold(tag, region) && i < v.region {
+				v.region = i
+			}
+		}
+		switch {
+		case strings.HasPrefix(tag, "Rev "):
+			rev := strings.TrimPrefix(tag, "Rev ")
+			if n, err := strconv.ParseFloat(rev, 64); err == nil {
+				v.revision = n
+			} else if len(rev) == 1 && rev[0] >= 'A' && rev[0] <= 'Z' {
+				v.revision = float64(rev[0]-'A') + 1
+			}
+		case len(tag) > 1 && tag[0] == 'v':
+			if n, err := strconv.ParseFloat(tag[1:], 64); err == nil {
+				v.revision = n
+			}
+		}
+	}
+	return v
+}
+
+// better tells if the version a should be preferred over b
+func better(a, b version, aOwned, bOwned bool) bool {
+	if a.unreleased != b.unreleased {
+		return !a.unreleased
+	}
+	if a.region != b.region {
+		return a.region < b.region
+	}
+	if a.revision != b.revision {
+		return a.revision > b.revision
+	}
+	return aOwned && !bOwned
+}
+
+// OneGameOneROM picks one version of each title of the audited systems, by
+// order of region priority. Released versions are preferred over betas and
+// prototypes, then the latest revision. When two versions are as good, the one
+// we have is picked.
+func OneGameOneROM(db *dat.DB, r Report, regions []string) []Pick {
+	var picks []Pick
+	for _, s := range r.Systems {
+		if s.Name == "" {
+			continue
+		}
+		owned := map[string]string{}
+		for _, row := range s.Rows {
+			if row.Status == Have {
+				owned[row.Game] = row.Path
+			}
+		}
+
+		best := map[string]dat.Game{}
+		var titles []string
+		for _, game := range db.Dats[s.Name].Games {
+			if len(game.ROMs) == 0 || isBadDump(game) {
+				continue
+			}
+			t := title(game.Name)
+			current, ok := best[t]
+			if !ok {
+				titles = append(titles, t)
+				best[t] = game
+				continue
+			}
+			_, gameOwned := owned[game.Name]
+			_, currentOwned := owned[current.Name]
+			if better(parseVersion(game.Name, regions), parseVersion(current.Name, regions), gameOwned, currentOwned) {
+				best[t] = game
+			}
+		}
+
+		sort.Strings(titles)
+		for _, t := range titles {
+			game := best[t]
+			picks = append(picks, Pick{
+				System: s.Name,
+				Title:  t,
+				Game:   game.Name,
+				Path:   owned[game.Name],
+			})
+		}
+	}
+	return picks
+}
+
+// WritePicksCSV writes the "one game, one ROM" view as CSV
+func WritePicksCSV(w io.Writer, picks []Pick) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"system", "title", "game", "path"})
+	for _, p := range picks {
+		c.Write([]string{p.System, p.Title, p.Game, p.Path})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WritePicksJSON writes the "one game, one ROM" view as indented JSON
+func WritePicksJSON(w io.Writer, picks []Pick) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(picks)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/libretro/ludo/audit"
+	"github.com/libretro/ludo/scanner"
+	"github.com/libretro/ludo/settings"
+)
+
+const auditUsage = `Usage:
+  %[1]s audit [--format csv|json] [--1g1r] [--regions USA,Europe,Japan] [--output file]
+`
+
+// runAudit implements the audit subcommand, it compares the scanned games with
+// the database and prints a report
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(auditUsage, os.Args[0])
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "csv", "Report format: csv or json")
+	oneGame := fs.Bool("1g1r", false, "Report one version of each game, by region priority")
+	regions := fs.String("regions", strings.Join(settings.Current.RegionPriority, ","), "Region priority of the 1G1R report")
+	output := fs.String("output", "", "Write the report to a file instead of the standard output")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	if *format != "csv" && *format != "json" {
+		return errors.New("unknown format " + *format)
+	}
+
+	db, err := scanner.LoadDB(settings.Current.DatabaseDirectory)
+	if err != nil {
+		return err
+	}
+	report := audit.Audit(&db, scanner.LoadIndex())
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *oneGame {
+		picks := audit.OneGameOneROM(&db, report, strings.Split(*regions, ","))
+		if *format == "json" {
+			return audit.WritePicksJSON(w, picks)
+		}
+		return audit.WritePicksCSV(w, picks)
+	}
+	if *format == "json" {
+		return report.WriteJSON(w)
+	}
+	return report.WriteCSV(w)
+}
@@ -0,0 +1,237 @@
+// Package collections manages the user defined lists of games, like the
+// favorites. Unlike playlists, collections are not built by the scanner but by
+// the user, and can contain games of different systems. Games are referenced
+// by path and CRC32, so a game that moved on the filesystem can be found again
+// in the playlists after a rescan.
+package collections
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/playlists"
+)
+
+// Favorites is the name of the collection that always exists
+const Favorites = "Favorites"
+
+// Game represents a game in a collection
+type Game struct {
+	Path   string // Absolute path of the game on the filesystem
+	Name   string // Human readable name of the game
+	System string // Name of the playlist of the game
+	CRC32  uint32 // Checksum of the game, used to find the game after a rescan
+}
+
+// Collection is a list of games chosen by the user
+type Collection []Game
+
+// Collections is a map of collections organized per name
+var Collections = map[string]Collection{}
+
+// Dir returns the directory where collections are stored
+func Dir() string {
+	return filepath.Join(xdg.DataHome, "ludo", "collections")
+}
+
+func path(name string) string {
+	return filepath.Join(Dir(), name+".csv")
+}
+
+// Load reads the collections in memory. The favorites are created if they
+// don't exist yet.
+func Load() {
+	Collections = map[string]Collection{Favorites: {}}
+	paths, err := filepath.Glob(filepath.Join(Dir(), "*.csv"))
+	if err != nil {
+		log.Println(err)
+	}
+	for _, p := range paths {
+		c, err := load(p)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		Collections[strings.TrimSuffix(filepath.Base(p), ".csv")] = c
+	}
+}
+
+func load(p string) (Collection, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(bufio.NewReader(file))
+	r.Comma = '\t'
+	c := Collection{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 4 {
+			continue
+		}
+		game := Game{Path: record[0], Name: record[1], System: record[2]}
+		if record[3] != "" {
+			u64, err := strconv.ParseUint(record[3], 16, 32)
+			if err != nil {
+				log.Println(err)
+			}
+			game.CRC32 = uint32(u64)
+		}
+		c = append(c, game)
+	}
+	return c, nil
+}
+
+// Save persists a collection as a csv file
+func Save(name string) error {
+	if err := os.MkdirAll(Dir(), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(bufio.NewWriter(file))
+	w.Comma = '\t'
+	for _, game := range Collections[name] {
+		w.Write([]string{
+			game.Path,
+			game.Name,
+			game.System,
+			strconv.FormatUint(uint64(game.CRC32), 16),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Names returns the names of the collections, the favorites first and the
+// others sorted
+func Names() []string {
+	names := []string{Favorites}
+	var others []string
+	for name := range Collections {
+		if name != Favorites {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// Create adds a new empty collection and saves it
+func Create(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, `/\`) || name[0] == '.' {
+		return errors.New("invalid collection name")
+	}
+	if _, ok := Collections[name]; ok {
+		return errors.New("collection already exists")
+	}
+	Collections[name] = Collection{}
+	return Save(name)
+}
+
+// Delete removes a collection and its file. The favorites can't be deleted.
+func Delete(name string) error {
+	if name == Favorites {
+		return errors.New("favorites can't be deleted")
+	}
+	delete(Collections, name)
+	err := os.Remove(path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Contains checks if a game is in a collection
+func Contains(name string, game Game) bool {
+	return index(Collections[name], game) >= 0
+}
+
+// index returns the position of a game in a collection, or -1. Games are
+// matched by path, or by CRC32 when both are known.
+func index(c Collection, game Game) int {
+	for i, g := range c {
+		if filepath.Clean(g.Path) == filepath.Clean(game.Path) ||
+			(game.CRC32 != 0 && g.CRC32 == game.CRC32) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add appends a game to a collection and saves it. It returns false if the
+// game was already there.
+func Add(name string, game Game) (bool, error) {
+	if Contains(name, game) {
+		return false, nil
+	}
+	Collections[name] = append(Collections[name], game)
+	return true, Save(name)
+}
+
+// Remove removes a game from a collection and saves it
+func Remove(name string, game Game) error {
+	c := Collections[name]
+	i := index(c, game)
+	if i < 0 {
+		return nil
+	}
+	Collections[name] = append(c[:i:i], c[i+1:]...)
+	return Save(name)
+}
+
+// Relocate updates the path of the games of a collection whose file
+// disappeared, using the playlists to find a game with the same CRC32. The
+// collection is saved if it changed.
+func Relocate(name string) error {
+	c := Collections[name]
+	changed := false
+	for i := range c {
+		if _, err := os.Stat(c[i].Path); err == nil || c[i].CRC32 == 0 {
+			continue
+		}
+		if g, system, ok := findByCRC(c[i].CRC32); ok {
+			c[i].Path = g.Path
+			c[i].System = system
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return Save(name)
+}
+
+// findByCRC looks for a game in the playlists by checksum
+func findByCRC(CRC32 uint32) (playlists.Game, string, bool) {
+	for _, p := range playlists.Paths() {
+		for _, g := range playlists.Get(p) {
+			if g.CRC32 == CRC32 {
+				return g, strings.TrimSuffix(filepath.Base(p), ".csv"), true
+			}
+		}
+	}
+	return playlists.Game{}, "", false
+}
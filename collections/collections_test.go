@@ -0,0 +1,113 @@
+package collections
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/playlists"
+)
+
+func withDataHome(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := xdg.DataHome
+	xdg.DataHome = dir
+	return dir, func() {
+		xdg.DataHome = old
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_SaveLoad(t *testing.T) {
+	_, cleanup := withDataHome(t)
+	defer cleanup()
+
+	Load()
+	if got := Names(); !reflect.DeepEqual(got, []string{Favorites}) {
+		t.Errorf("Names() = %v, want only the favorites", got)
+	}
+
+	a := Game{Path: "/roms/a.sfc", Name: "A (USA)", System: "Nintendo - SNES", CRC32: 0xdeadbeef}
+	b := Game{Path: "/roms/b.md", Name: "B (Europe)", System: "Sega - Mega Drive - Genesis"}
+	if added, err := Add(Favorites, a); !added || err != nil {
+		t.Fatalf("Add() = %v, %v", added, err)
+	}
+	if added, _ := Add(Favorites, Game{Path: "/elsewhere/a.sfc", CRC32: 0xdeadbeef}); added {
+		t.Error("Add() should deduplicate by CRC32")
+	}
+	if err := Create("Zelda"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Create("RPG"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Create("RPG"); err == nil {
+		t.Error("Create() should refuse existing collections")
+	}
+	if err := Create("../RPG"); err == nil {
+		t.Error("Create() should refuse names with separators")
+	}
+	Add("RPG", a)
+	Add("RPG", b)
+	if err := Remove("RPG", a); err != nil {
+		t.Fatal(err)
+	}
+
+	Load()
+	want := map[string]Collection{
+		Favorites: {a},
+		"RPG":     {b},
+		"Zelda":   {},
+	}
+	if !reflect.DeepEqual(Collections, want) {
+		t.Errorf("Load() = %v, want %v", Collections, want)
+	}
+	if got := Names(); !reflect.DeepEqual(got, []string{Favorites, "RPG", "Zelda"}) {
+		t.Errorf("Names() = %v", got)
+	}
+
+	if err := Delete(Favorites); err == nil {
+		t.Error("Delete() should refuse to delete the favorites")
+	}
+	if err := Delete("Zelda"); err != nil {
+		t.Fatal(err)
+	}
+	Load()
+	if _, ok := Collections["Zelda"]; ok {
+		t.Error("Delete() should remove the file")
+	}
+}
+
+func Test_Relocate(t *testing.T) {
+	dir, cleanup := withDataHome(t)
+	defer cleanup()
+
+	moved := filepath.Join(dir, "a.sfc")
+	if err := ioutil.WriteFile(moved, []byte("rom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	csv := filepath.Join(dir, "Nintendo - SNES.csv")
+	playlists.Set(csv, playlists.Playlist{{Path: moved, Name: "A (USA)", CRC32: 0xdeadbeef}})
+	defer playlists.Remove(csv)
+
+	Collections = map[string]Collection{Favorites: {
+		{Path: "/gone/a.sfc", Name: "A (USA)", System: "Old", CRC32: 0xdeadbeef},
+		{Path: "/gone/b.sfc", Name: "B (USA)", System: "Old", CRC32: 0x1234},
+	}}
+	if err := Relocate(Favorites); err != nil {
+		t.Fatal(err)
+	}
+	want := Collection{
+		{Path: moved, Name: "A (USA)", System: "Nintendo - SNES", CRC32: 0xdeadbeef},
+		{Path: "/gone/b.sfc", Name: "B (USA)", System: "Old", CRC32: 0x1234},
+	}
+	if !reflect.DeepEqual(Collections[Favorites], want) {
+		t.Errorf("Relocate() = %v, want %v", Collections[Favorites], want)
+	}
+}
@@ -0,0 +1,378 @@
+// Package control allows driving Ludo remotely. It exposes commands to load
+// cores and games, pause, reset, save and load states, take screenshots and
+// press buttons. Commands are received from other goroutines, like the HTTP
+// server or the RetroArch compatible UDP interface, but they are always
+// executed on the main loop thread.
+package control
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/libretro/ludo/core"
+	"github.com/libretro/ludo/history"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/memory"
+	"github.com/libretro/ludo/savestates"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+	"github.com/libretro/ludo/video"
+)
+
+var vid *video.Video
+
+// onGameLoaded is called after a game has been loaded remotely, it allows the
+// menu to navigate to the quick menu.
+var onGameLoaded func()
+
+// queue holds the commands waiting to be executed on the main thread
+var queue = make(chan func(), 64)
+
+// held is the number of frames left for each button pressed remotely
+var held [input.MaxPlayers]map[uint32]int
+
+// fps is measured in Process, once per second
+var (
+	fps        float64
+	frames     int
+	lastSample time.Time
+)
+
+// Init is there mainly for dependency injection.
+// Call Init before calling other functions of this package.
+func Init(v *video.Video, gameLoadedCb func()) {
+	vid = v
+	onGameLoaded = gameLoadedCb
+	for i := range held {
+		held[i] = map[uint32]int{}
+	}
+}
+
+// Process executes the pending commands and applies the buttons pressed
+// remotely. It is meant to be called once per frame, after input.Poll.
+func Process() {
+	for {
+		select {
+		case f := <-queue:
+			f()
+		default:
+			applyInput()
+			measureFPS()
+			return
+		}
+	}
+}
+
+// run schedules f on the main thread and waits for its result
+func run(f func() (interface{}, error)) (interface{}, error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	timeout := time.After(10 * time.Second)
+	select {
+	case queue <- func() {
+		v, err := f()
+		done <- result{v, err}
+	}:
+	case <-timeout:
+		return nil, errors.New("timeout waiting for the main loop")
+	}
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timeout:
+		return nil, errors.New("timeout waiting for the main loop")
+	}
+}
+
+func applyInput() {
+	if !state.CoreRunning || state.MenuActive {
+		return
+	}
+	for p := range held {
+		for id, n := range held[p] {
+			input.NewState[p][id] = 1
+			if n <= 1 {
+				delete(held[p], id)
+			} else {
+				held[p][id] = n - 1
+			}
+		}
+	}
+}
+
+func measureFPS() {
+	if !state.CoreRunning || state.MenuActive {
+		frames = 0
+		fps = 0
+		lastSample = time.Now()
+		return
+	}
+	frames++
+	if elapsed := time.Since(lastSample); elapsed >= time.Second {
+		fps = float64(frames) / elapsed.Seconds()
+		frames = 0
+		lastSample = time.Now()
+	}
+}
+
+// Status describes what Ludo is currently running
+type Status struct {
+	CoreName    string  `json:"core_name"`
+	CoreVersion string  `json:"core_version"`
+	CorePath    string  `json:"core_path"`
+	GamePath    string  `json:"game_path"`
+	Running     bool    `json:"running"`
+	Paused      bool    `json:"paused"`
+	FPS         float64 `json:"fps"`
+}
+
+// GetStatus returns the name of the core, the path of the game and the
+// current frame rate
+func GetStatus() (Status, error) {
+	v, err := run(func() (interface{}, error) {
+		s := Status{
+			CorePath: state.CorePath,
+			GamePath: state.GamePath,
+			Running:  state.CoreRunning,
+			Paused:   state.CoreRunning && state.MenuActive,
+			FPS:      fps,
+		}
+		if state.Core != nil {
+			si := state.Core.GetSystemInfo()
+			s.CoreName = si.LibraryName
+			s.CoreVersion = si.LibraryVersion
+		}
+		return s, nil
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	return v.(Status), nil
+}
+
+// LoadCore loads a libretro core
+func LoadCore(path string) error {
+	_, err := run(func() (interface{}, error) {
+		return nil, core.Load(path)
+	})
+	return err
+}
+
+// LoadGame loads a game with the current core and starts playing
+func LoadGame(path string) error {
+	_, err := run(func() (interface{}, error) {
+		if state.Core == nil {
+			return nil, errors.New("no core loaded")
+		}
+		if err := core.LoadGame(path); err != nil {
+			return nil, err
+		}
+		history.Push(history.Game{
+			Path:     path,
+			Name:     utils.FileName(path),
+			CorePath: state.CorePath,
+		})
+		if onGameLoaded != nil {
+			onGameLoaded()
+		}
+		state.MenuActive = false
+		return nil, nil
+	})
+	return err
+}
+
+// Pause pauses or resumes the game. The menu is displayed while paused.
+func Pause(paused bool) error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		state.MenuActive = paused
+		state.FastForward = false
+		return nil, nil
+	})
+	return err
+}
+
+// Reset restarts the current game
+func Reset() error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		state.Core.Reset()
+		return nil, nil
+	})
+	return err
+}
+
+// SaveState saves the state of the game and returns the path of the
+// savestate. A dated name is used if name is empty.
+func SaveState(name string) (string, error) {
+	v, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		if name == "" {
+			name = utils.DatedName(state.GamePath)
+		}
+		if err := savestates.Save(name); err != nil {
+			return nil, err
+		}
+		return filepath.Join(settings.Current.SavestatesDirectory, name+".state"), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// LoadState loads a savestate from its path
+func LoadState(path string) error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		return nil, savestates.Load(path)
+	})
+	return err
+}
+
+// Screenshot captures the game screen and returns the path of the image
+func Screenshot() (string, error) {
+	v, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		name := utils.DatedName(state.GamePath)
+		if err := vid.TakeScreenshot(name); err != nil {
+			return nil, err
+		}
+		return filepath.Join(settings.Current.ScreenshotsDirectory, name+".png"), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// Press holds a button of a given port during a number of frames
+func Press(port int, button string, frames int) error {
+	id, ok := input.Buttons[button]
+	if !ok {
+		return errors.New("unknown button " + button)
+	}
+	if port < 0 || port >= input.MaxPlayers {
+		return errors.New("invalid port")
+	}
+	if frames <= 0 {
+		frames = 1
+	}
+	_, err := run(func() (interface{}, error) {
+		held[port][id] = frames
+		return nil, nil
+	})
+	return err
+}
+
+// TogglePause pauses the game if it is running, or resumes it if paused
+func TogglePause() error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		state.MenuActive = !state.MenuActive
+		state.FastForward = false
+		return nil, nil
+	})
+	return err
+}
+
+// ToggleFastForward enables or disables fast forward
+func ToggleFastForward() error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning || state.MenuActive {
+			return nil, errors.New("no game running")
+		}
+		state.FastForward = !state.FastForward
+		return nil, nil
+	})
+	return err
+}
+
+// SaveSlot saves the state of the game in the current slot
+func SaveSlot() error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		name := savestates.SlotName(state.GamePath, savestates.Slot)
+		if err := vid.TakeScreenshot(name); err != nil {
+			return nil, err
+		}
+		return nil, savestates.SaveSlot()
+	})
+	return err
+}
+
+// LoadSlot loads the state of the game from the current slot
+func LoadSlot() error {
+	_, err := run(func() (interface{}, error) {
+		if !state.CoreRunning {
+			return nil, errors.New("no game running")
+		}
+		return nil, savestates.LoadSlot()
+	})
+	return err
+}
+
+// ChangeSlot selects the next slot if direction is positive, or the previous
+// one otherwise
+func ChangeSlot(direction int) error {
+	_, err := run(func() (interface{}, error) {
+		if direction > 0 {
+			savestates.NextSlot()
+		} else {
+			savestates.PrevSlot()
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Quit closes Ludo
+func Quit() error {
+	_, err := run(func() (interface{}, error) {
+		vid.SetShouldClose(true)
+		return nil, nil
+	})
+	return err
+}
+
+// ReadMemory reads length bytes of the emulated system memory at addr
+func ReadMemory(addr uintptr, length uint) ([]byte, error) {
+	v, err := run(func() (interface{}, error) {
+		return memory.ReadAddress(addr, length)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// WriteMemory writes data to the emulated system memory at addr and returns
+// the number of bytes written
+func WriteMemory(addr uintptr, data []byte) (int, error) {
+	v, err := run(func() (interface{}, error) {
+		return memory.WriteAddress(addr, data)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
@@ -0,0 +1,272 @@
+package control
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/settings"
+)
+
+var server *http.Server
+
+// params holds the arguments of all the commands
+type params struct {
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+	Button string `json:"button"`
+	Frames int    `json:"frames"`
+}
+
+// commands maps command names to their implementation. A command can be
+// called with a POST on /<name> or with the JSON-RPC endpoint /rpc.
+var commands = map[string]func(p params) (interface{}, error){
+	"status": func(p params) (interface{}, error) {
+		return GetStatus()
+	},
+	"load_core": func(p params) (interface{}, error) {
+		// Loading a core runs its code, only trust the cores directory
+		if !inDirectory(settings.Current.CoresDirectory, p.Path) {
+			return nil, errors.New("cores can only be loaded from the cores directory")
+		}
+		return nil, LoadCore(p.Path)
+	},
+	"load_game": func(p params) (interface{}, error) {
+		return nil, LoadGame(p.Path)
+	},
+	"pause": func(p params) (interface{}, error) {
+		return nil, Pause(true)
+	},
+	"resume": func(p params) (interface{}, error) {
+		return nil, Pause(false)
+	},
+	"reset": func(p params) (interface{}, error) {
+		return nil, Reset()
+	},
+	"save_state": func(p params) (interface{}, error) {
+		return SaveState(p.Name)
+	},
+	"load_state": func(p params) (interface{}, error) {
+		return nil, LoadState(p.Path)
+	},
+	"screenshot": func(p params) (interface{}, error) {
+		return Screenshot()
+	},
+	"press": func(p params) (interface{}, error) {
+		return nil, Press(p.Port, p.Button, p.Frames)
+	},
+}
+
+// response is the body of the replies of the HTTP endpoints
+type response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// rpcRequest is a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  params          `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("[Control]:", err)
+	}
+}
+
+// inDirectory checks if path is a file of dir or of its subdirectories, once
+// symbolic links are resolved
+func inDirectory(dir, path string) bool {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+// token returns the secret that clients must send in the Authorization
+// header. It is generated and saved in the settings the first time.
+func token() (string, error) {
+	if settings.Current.ControlServerToken != "" {
+		return settings.Current.ControlServerToken, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	settings.Current.ControlServerToken = hex.EncodeToString(b)
+	if err := settings.Save(); err != nil {
+		log.Println("[Control]:", err)
+	}
+	return settings.Current.ControlServerToken, nil
+}
+
+// guard rejects the requests that could come from a web page open in a
+// browser. Browsers send an Origin header with cross site requests, and can't
+// send a JSON content type or an Authorization header without a preflight.
+// The Host check defeats DNS rebinding.
+func guard(secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeJSON(w, http.StatusForbidden, response{Error: "cross origin requests are not allowed"})
+			return
+		}
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if host != "127.0.0.1" && host != "localhost" {
+			writeJSON(w, http.StatusForbidden, response{Error: "invalid host"})
+			return
+		}
+		auth := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(auth, []byte("Bearer "+secret)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, response{Error: "invalid token"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mt != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, response{Error: "use application/json"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleCommand serves the REST-like endpoints, like POST /load_game
+func handleCommand(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	cmd, ok := commands[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, response{Error: "unknown command"})
+		return
+	}
+	if name != "status" && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, response{Error: "use POST"})
+		return
+	}
+
+	var p params
+	if r.Method == http.MethodPost && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+			return
+		}
+	}
+
+	v, err := cmd(p)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, response{Result: v})
+}
+
+// handleRPC serves the JSON-RPC 2.0 endpoint
+func handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, response{Error: "use POST"})
+		return
+	}
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: -32700, Message: err.Error()},
+			ID:      json.RawMessage("null"),
+		})
+		return
+	}
+
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if cmd, ok := commands[req.Method]; !ok {
+		resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+	} else if v, err := cmd(req.Params); err != nil {
+		resp.Error = &rpcError{Code: -32000, Message: err.Error()}
+	} else {
+		resp.Result = v
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Start starts the control server. It only listens on localhost, and clients
+// must send the token from the settings as a bearer token.
+func Start() error {
+	if server != nil {
+		return errors.New("control server already running")
+	}
+
+	secret, err := token()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", guard(secret, handleRPC))
+	mux.HandleFunc("/", guard(secret, handleCommand))
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(settings.Current.ControlServerPort))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server = &http.Server{Handler: mux}
+	go func(s *http.Server) {
+		if err := s.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Println("[Control]:", err)
+		}
+	}(server)
+
+	log.Println("[Control]: Listening on", addr, "- clients must send control_server_token from the settings")
+	return nil
+}
+
+// Stop stops the control server
+func Stop() error {
+	if server == nil {
+		return nil
+	}
+	err := server.Close()
+	server = nil
+	return err
+}
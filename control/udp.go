@@ -0,0 +1,201 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+var conn *net.UDPConn
+
+// udpCommands implements the RetroArch network commands. Each command returns
+// the reply to send back, or an empty string if the command has no reply.
+var udpCommands = map[string]func(args []string) string{
+	"PAUSE_TOGGLE": func(args []string) string {
+		logError(TogglePause())
+		return ""
+	},
+	"MENU_TOGGLE": func(args []string) string {
+		logError(TogglePause())
+		return ""
+	},
+	"FAST_FORWARD": func(args []string) string {
+		logError(ToggleFastForward())
+		return ""
+	},
+	"SAVE_STATE": func(args []string) string {
+		logError(SaveSlot())
+		return ""
+	},
+	"LOAD_STATE": func(args []string) string {
+		logError(LoadSlot())
+		return ""
+	},
+	"STATE_SLOT_PLUS": func(args []string) string {
+		logError(ChangeSlot(1))
+		return ""
+	},
+	"STATE_SLOT_MINUS": func(args []string) string {
+		logError(ChangeSlot(-1))
+		return ""
+	},
+	"SCREENSHOT": func(args []string) string {
+		_, err := Screenshot()
+		logError(err)
+		return ""
+	},
+	"RESET": func(args []string) string {
+		logError(Reset())
+		return ""
+	},
+	"QUIT": func(args []string) string {
+		logError(Quit())
+		return ""
+	},
+	"GET_STATUS":        getStatus,
+	"READ_CORE_MEMORY":  readCoreMemory,
+	"WRITE_CORE_MEMORY": writeCoreMemory,
+}
+
+func logError(err error) {
+	if err != nil {
+		log.Println("[Control]:", err)
+	}
+}
+
+// getStatus replies with GET_STATUS PLAYING|PAUSED <core>,<game> or
+// GET_STATUS CONTENTLESS if no game is running
+func getStatus(args []string) string {
+	s, err := GetStatus()
+	if err != nil || !s.Running {
+		return "GET_STATUS CONTENTLESS"
+	}
+	status := "PLAYING"
+	if s.Paused {
+		status = "PAUSED"
+	}
+	return fmt.Sprintf("GET_STATUS %s %s,%s", status, s.CoreName, utils.FileName(s.GamePath))
+}
+
+// readCoreMemory handles READ_CORE_MEMORY <hex address> <length>
+func readCoreMemory(args []string) string {
+	if len(args) != 2 {
+		return "READ_CORE_MEMORY -1 invalid arguments"
+	}
+	addr, err := strconv.ParseUint(strings.TrimPrefix(args[0], "0x"), 16, 64)
+	if err != nil {
+		return "READ_CORE_MEMORY -1 invalid address"
+	}
+	length, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil || length == 0 {
+		return fmt.Sprintf("READ_CORE_MEMORY %x -1 invalid length", addr)
+	}
+
+	bytes, err := ReadMemory(uintptr(addr), uint(length))
+	if err != nil {
+		return fmt.Sprintf("READ_CORE_MEMORY %x -1 %s", addr, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "READ_CORE_MEMORY %x", addr)
+	for _, v := range bytes {
+		fmt.Fprintf(&b, " %02x", v)
+	}
+	return b.String()
+}
+
+// writeCoreMemory handles WRITE_CORE_MEMORY <hex address> <hex byte>...
+func writeCoreMemory(args []string) string {
+	if len(args) < 2 {
+		return "WRITE_CORE_MEMORY -1 invalid arguments"
+	}
+	addr, err := strconv.ParseUint(strings.TrimPrefix(args[0], "0x"), 16, 64)
+	if err != nil {
+		return "WRITE_CORE_MEMORY -1 invalid address"
+	}
+
+	data := make([]byte, len(args)-1)
+	for i, arg := range args[1:] {
+		v, err := strconv.ParseUint(strings.TrimPrefix(arg, "0x"), 16, 8)
+		if err != nil {
+			return fmt.Sprintf("WRITE_CORE_MEMORY %x -1 invalid byte %s", addr, arg)
+		}
+		data[i] = byte(v)
+	}
+
+	n, err := WriteMemory(uintptr(addr), data)
+	if err != nil {
+		return fmt.Sprintf("WRITE_CORE_MEMORY %x -1 %s", addr, err)
+	}
+	return fmt.Sprintf("WRITE_CORE_MEMORY %x %d", addr, n)
+}
+
+// handleDatagram executes the commands of a datagram, one per line, and
+// returns the replies
+func handleDatagram(msg string) []string {
+	var replies []string
+	for _, line := range strings.Split(msg, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, ok := udpCommands[fields[0]]
+		if !ok {
+			log.Println("[Control]: Unknown network command", fields[0])
+			continue
+		}
+		if reply := cmd(fields[1:]); reply != "" {
+			replies = append(replies, reply)
+		}
+	}
+	return replies
+}
+
+// StartNetworkCommands listens for RetroArch compatible network commands on
+// UDP. It only listens on localhost.
+func StartNetworkCommands() error {
+	if conn != nil {
+		return errors.New("network commands already enabled")
+	}
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: settings.Current.NetworkCommandsPort}
+	c, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn = c
+
+	go func(c *net.UDPConn) {
+		buf := make([]byte, 4096)
+		for {
+			n, from, err := c.ReadFromUDP(buf)
+			if err != nil {
+				return // closed
+			}
+			for _, reply := range handleDatagram(string(buf[:n])) {
+				if _, err := c.WriteToUDP([]byte(reply+"\n"), from); err != nil {
+					log.Println("[Control]:", err)
+				}
+			}
+		}
+	}(c)
+
+	log.Println("[Control]: Listening for network commands on", addr)
+	return nil
+}
+
+// StopNetworkCommands stops listening for network commands
+func StopNetworkCommands() error {
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	conn = nil
+	return err
+}
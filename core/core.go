@@ -4,20 +4,32 @@
 package core
 
 import (
+	"encoding/binary"
 	"errors"
+	"hash/crc32"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/history"
 	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/libretro"
+	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/options"
 	"github.com/libretro/ludo/patch"
 	"github.com/libretro/ludo/savefiles"
+	"github.com/libretro/ludo/savestates"
+	"github.com/libretro/ludo/savesync"
+	"github.com/libretro/ludo/scripting"
+	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
 	"github.com/libretro/ludo/video"
 
 	"github.com/mholt/archiver/v3"
@@ -134,6 +146,32 @@ func unarchiveGame(filename string) (string, int64, error) {
 	return path, size, err
 }
 
+// identityPrefix is the part of a fullpath content hashed to identify it
+const identityPrefix = 1 << 20
+
+// contentIdentity returns a CRC32 identifying a file given to a fullpath core.
+// Disc images can weigh gigabytes, so only the size of the file and its first
+// MiB are hashed, which is enough to tell the contents apart.
+func contentIdentity(path string) (uint32, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+	fi, err := fd.Stat()
+	if err != nil {
+		return 0, err
+	}
+	h := crc32.NewIEEE()
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(fi.Size()))
+	h.Write(size)
+	if _, err := io.CopyN(h, fd, identityPrefix); err != nil && err != io.EOF {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
 // LoadGame loads a game. A core has to be loaded first.
 func LoadGame(gamePath string) error {
 	if _, err := os.Stat(gamePath); os.IsNotExist(err) {
@@ -153,17 +191,39 @@ func LoadGame(gamePath string) error {
 		return err
 	}
 
+	var crc uint32
+	var patches []string
 	if !si.NeedFullpath {
 		bytes, err := ioutil.ReadFile(gi.Path)
 		if err != nil {
 			return err
 		}
 
-		if patched, _ := patch.Try(gamePath, bytes); patched != nil {
-			gi.Size = int64(len(*patched))
-			gi.SetData(*patched)
-		} else {
-			gi.SetData(bytes)
+		if patched, paths := softpatch(gamePath, gi.Path, bytes); patched != nil {
+			bytes = *patched
+			gi.Size = int64(len(bytes))
+			patches = paths
+		}
+		gi.SetData(bytes)
+		crc = crc32.ChecksumIEEE(bytes)
+	} else if settings.Current.SoftpatchFullpath {
+		// The core needs a path, so the patched ROM is written to a cache
+		path, paths, err := patch.Fullpath(gamePath, gi.Path)
+		if err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Patch", err.Error())
+		} else if path != "" {
+			gi.Path = path
+			if fi, err := os.Stat(path); err == nil {
+				gi.Size = fi.Size()
+			}
+			patches = paths
+		}
+	}
+
+	if si.NeedFullpath {
+		// The savestates rely on the CRC to detect another content
+		if crc, err = contentIdentity(gi.Path); err != nil {
+			log.Println("[Core]: Checksum failed:", err)
 		}
 	}
 
@@ -191,6 +251,8 @@ func LoadGame(gamePath string) error {
 	state.CoreRunning = true
 	state.FastForward = false
 	state.GamePath = gamePath
+	state.GameCRC = crc
+	state.Patches = patches
 
 	state.Core.SetControllerPortDevice(0, libretro.DeviceJoypad)
 	state.Core.SetControllerPortDevice(1, libretro.DeviceJoypad)
@@ -199,8 +261,13 @@ func LoadGame(gamePath string) error {
 	state.Core.SetControllerPortDevice(4, libretro.DeviceJoypad)
 
 	log.Println("[Core]: Game loaded: " + gamePath)
+	pullGame(gamePath)
 	savefiles.LoadSRAM()
 
+	if err := scripting.Load(gamePath); err != nil {
+		log.Println("[Scripting]:", err)
+	}
+
 	return nil
 }
 
@@ -215,18 +282,99 @@ func Unload() {
 	}
 }
 
+// autoSave saves the state of the game before unloading it, so the game can be
+// resumed later. The path of the savestate is kept in the history.
+func autoSave() {
+	switch settings.Current.AutoResume {
+	case "Always", "Ask":
+	default:
+		return
+	}
+	if state.GamePath == "" || state.Core.SerializeSize() == 0 {
+		return
+	}
+	name := savestates.SlotName(state.GamePath, savestates.AutoSlot)
+	if err := vid.TakeScreenshot(name); err != nil {
+		log.Println("[Core]: Auto save screenshot failed:", err)
+	}
+	if err := savestates.Save(name); err != nil {
+		log.Println("[Core]: Auto save failed:", err)
+		return
+	}
+	path := savestates.SlotPath(state.GamePath, savestates.AutoSlot)
+	if err := history.SetSavestate(state.GamePath, path); err != nil {
+		log.Println("[Core]: Auto save failed:", err)
+	}
+}
+
+// syncs counts the uploads of saves running in the background
+var syncs sync.WaitGroup
+
+// pullTimeout bounds the time spent downloading the saves of a game on load
+const pullTimeout = 10 * time.Second
+
+// pullGame downloads the saves of a game before its SRAM is loaded. A slow
+// server delays the game by about pullTimeout, the game then starts with the
+// local saves.
+func pullGame(gamePath string) {
+	if !savesync.Enabled() || gamePath == "" {
+		return
+	}
+	if err := savesync.PullGame(gamePath, pullTimeout); err != nil {
+		ntf.DisplayAndLog(ntf.Warning, "Sync", "Sync failed: %s", err.Error())
+	}
+}
+
+// pushGame uploads the saves of a game in the background, so a slow server
+// doesn't freeze the menu. The result is reported through the notifications.
+func pushGame(gamePath string) {
+	if !savesync.Enabled() || gamePath == "" {
+		return
+	}
+	syncs.Add(1)
+	go func() {
+		defer syncs.Done()
+		n := ntf.DisplayAndLog(ntf.Info, "Sync", "Uploading saves of %s", utils.FileName(gamePath))
+		if err := savesync.PushGame(gamePath); err != nil {
+			n.Update(ntf.Warning, "Sync failed: %s", err.Error())
+			return
+		}
+		n.Update(ntf.Success, "Saves uploaded")
+	}()
+}
+
+// WaitSync waits for the uploads of saves running in the background to finish
+func WaitSync() {
+	syncs.Wait()
+}
+
 // UnloadGame unloads a game.
 func UnloadGame() {
 	if state.CoreRunning {
+		autoSave()
+		scripting.Unload()
 		savefiles.SaveSRAM()
+		pushGame(state.GamePath)
 		state.Core.UnloadGame()
 		state.GamePath = ""
+		state.GameCRC = 0
+		state.Patches = nil
 		state.CoreRunning = false
 		vid.ResetPitch()
 		vid.ResetRot()
 	}
 }
 
+// softpatch applies the patches of a game, errors are reported to the user
+func softpatch(gamePath, romPath string, bytes []byte) (*[]byte, []string) {
+	patched, paths, err := patch.Try(gamePath, romPath, bytes)
+	if err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Patch", err.Error())
+		return nil, nil
+	}
+	return patched, paths
+}
+
 // getGameInfo opens a rom and return the libretro.GameInfo needed to launch it
 func getGameInfo(filename string, blockExtract bool) (*libretro.GameInfo, error) {
 	file, err := os.Open(filename)
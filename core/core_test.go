@@ -1,6 +1,7 @@
 package core
 
 import (
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -237,3 +238,38 @@ func Test_coreLoadGame(t *testing.T) {
 		}
 	})
 }
+
+func Test_contentIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	identity := func(content []byte) uint32 {
+		path := filepath.Join(dir, "disc.iso")
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		crc, err := contentIdentity(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crc
+	}
+
+	small := identity([]byte("cue sheet"))
+	if small == identity([]byte("other sheet")) {
+		t.Errorf("contentIdentity() is the same for different contents")
+	}
+
+	big := make([]byte, identityPrefix+10)
+	a := identity(big)
+	big[identityPrefix+5] = 1
+	if identity(big) != a {
+		t.Errorf("contentIdentity() read past the prefix")
+	}
+	if identity(big[:identityPrefix+9]) == a {
+		t.Errorf("contentIdentity() ignores the size")
+	}
+}
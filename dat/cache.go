@@ -0,0 +1,104 @@
+package dat
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Source describes a dat file, the cache is only used if none of the dat
+// files changed since it was written
+type Source struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Sources maps the dat files to their description
+type Sources map[string]Source
+
+// Fingerprint returns a hash of the sources, it changes when a dat file is
+// added, removed or modified, or when the parsers change
+func (s Sources) Fingerprint() string {
+	var names []string
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha1.New()
+	fmt.Fprintf(h, "%d\n", cacheVersion)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", name, s[name].Size, s[name].ModTime.UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheVersion must be incremented when the parsers or the types stored in
+// the cache change, so caches written by older versions are parsed again
+const cacheVersion = 3
+
+// cache is what is stored in the cache file, the parsed Dats and their indexes
+type cache struct {
+	Version int
+	Sources Sources
+	Dats    map[string]Dat
+	Index   index
+}
+
+// ReadCache reads a DB from the cache file at path. It fails if the cache was
+// written for different sources.
+func ReadCache(path string, sources Sources) (DB, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return DB{}, err
+	}
+	var c cache
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&c); err != nil {
+		return DB{}, err
+	}
+	if c.Version != cacheVersion {
+		return DB{}, errors.New("cache written by another version")
+	}
+	if !sameSources(c.Sources, sources) {
+		return DB{}, errors.New("outdated cache")
+	}
+	return DB{Dats: c.Dats, index: c.Index}, nil
+}
+
+// WriteCache writes the DB and its indexes to the cache file at path
+func (db *DB) WriteCache(path string, sources Sources) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache{cacheVersion, sources, db.Dats, db.index}); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sameSources compares sources, ignoring the monotonic clock and location of
+// the modification times
+func sameSources(a, b Sources) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, s := range a {
+		o, ok := b[name]
+		if !ok || s.Size != o.Size || !s.ModTime.Equal(o.ModTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package dat
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ClrMamePro dats are made of blocks of key value pairs, values can be
+// quoted strings, words, or nested blocks:
+//
+//	game (
+//		name "Game (USA)"
+//		rom ( name "Game (USA).sfc" size 1048576 crc 1234ABCD )
+//	)
+
+// cmpEntry is a key value pair of a ClrMamePro dat, Block is set when the
+// value is a nested block
+type cmpEntry struct {
+	Key   string
+	Value string
+	Block []cmpEntry
+}
+
+// cmpTokens splits a ClrMamePro dat in words, quoted strings and parentheses
+func cmpTokens(data []byte) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(data) && data[j] != '"' {
+				j++
+			}
+			if j == len(data) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, string(data[i+1:j]))
+			i = j + 1
+		default:
+			j := i
+			for j < len(data) && data[j] != ' ' && data[j] != '\t' && data[j] != '\r' &&
+				data[j] != '\n' && data[j] != '(' && data[j] != ')' {
+				j++
+			}
+			tokens = append(tokens, string(data[i:j]))
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// cmpBlock parses the entries of a block until its closing parenthesis
+func cmpBlock(tokens []string, i *int, nested bool) ([]cmpEntry, error) {
+	var entries []cmpEntry
+	for *i < len(tokens) {
+		key := tokens[*i]
+		*i++
+		if key == ")" {
+			if !nested {
+				return nil, errors.New("unexpected )")
+			}
+			return entries, nil
+		}
+		if *i >= len(tokens) {
+			return nil, errors.New("missing value for " + key)
+		}
+		value := tokens[*i]
+		*i++
+		if value != "(" {
+			entries = append(entries, cmpEntry{Key: key, Value: value})
+			continue
+		}
+		block, err := cmpBlock(tokens, i, true)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cmpEntry{Key: key, Block: block})
+	}
+	if nested {
+		return nil, errors.New("missing )")
+	}
+	return entries, nil
+}
+
+// cmpValue returns the first value of key in a block
+func cmpValue(block []cmpEntry, keys ...string) string {
+	for _, key := range keys {
+		for _, e := range block {
+			if e.Key == key && e.Block == nil {
+				return e.Value
+			}
+		}
+	}
+	return ""
+}
+
+// ParseClrMamePro parses the content of a ClrMamePro dat, like the dats and
+// metadats of the libretro database
+func ParseClrMamePro(data []byte) (Dat, error) {
+	var output Dat
+	tokens, err := cmpTokens(data)
+	if err != nil {
+		return output, err
+	}
+	i := 0
+	entries, err := cmpBlock(tokens, &i, false)
+	if err != nil {
+		return output, err
+	}
+
+	for _, e := range entries {
+		if e.Key != "game" && e.Key != "machine" {
+			continue
+		}
+		game := Game{
+			Name:        cmpValue(e.Block, "name"),
+			Description: cmpValue(e.Block, "description", "name"),
+			Serial:      cmpValue(e.Block, "serial"),
+			Genre:       cmpValue(e.Block, "genre"),
+			Developer:   cmpValue(e.Block, "developer"),
+			Year:        cmpValue(e.Block, "releaseyear", "year"),
+		}
+		for _, r := range e.Block {
+			if r.Key != "rom" || r.Block == nil {
+				continue
+			}
+			rom := ROM{
+				Name:   cmpValue(r.Block, "name"),
+				MD5:    cmpValue(r.Block, "md5"),
+				SHA1:   cmpValue(r.Block, "sha1"),
+				Serial: cmpValue(r.Block, "serial"),
+				Status: cmpValue(r.Block, "status", "flags"),
+			}
+			if crc := cmpValue(r.Block, "crc"); crc != "" {
+				u64, err := strconv.ParseUint(crc, 16, 32)
+				if err != nil {
+					return output, err
+				}
+				rom.CRC = CRC(u64)
+			}
+			game.ROMs = append(game.ROMs, rom)
+		}
+		output.Games = append(output.Games, game)
+	}
+	return output, nil
+}
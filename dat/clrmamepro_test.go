@@ -0,0 +1,64 @@
+package dat
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testClrMamePro = `clrmamepro (
+	name "Sony - PlayStation"
+	version 20200101
+)
+
+game (
+	name "Disc Game (USA)"
+	description "Disc Game (USA)"
+	serial "SLUS-01234"
+	genre "Action"
+	developer "Studio"
+	releaseyear 1999
+	rom ( name "Disc Game (USA).cue" size 98 crc 1234ABCD md5 0123456789abcdef0123456789abcdef sha1 AAAA )
+)
+
+game (
+	name "Other Game (Europe)"
+	rom ( crc deadbeef )
+)
+`
+
+func Test_ParseClrMamePro(t *testing.T) {
+	d, err := ParseClrMamePro([]byte(testClrMamePro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Game{
+		{
+			Name:        "Disc Game (USA)",
+			Description: "Disc Game (USA)",
+			Serial:      "SLUS-01234",
+			Genre:       "Action",
+			Developer:   "Studio",
+			Year:        "1999",
+			ROMs: []ROM{{
+				Name: "Disc Game (USA).cue",
+				CRC:  0x1234abcd,
+				MD5:  "0123456789abcdef0123456789abcdef",
+				SHA1: "AAAA",
+			}},
+		},
+		{
+			Name:        "Other Game (Europe)",
+			Description: "Other Game (Europe)",
+			ROMs:        []ROM{{CRC: 0xdeadbeef}},
+		},
+	}
+	if !reflect.DeepEqual(d.Games, want) {
+		t.Errorf("ParseClrMamePro() = %+v, want %+v", d.Games, want)
+	}
+
+	for _, invalid := range []string{`game ( name "Game"`, `game ( name "Game )`, `)`, `game`} {
+		if _, err := ParseClrMamePro([]byte(invalid)); err == nil {
+			t.Errorf("ParseClrMamePro(%q) should fail", invalid)
+		}
+	}
+}
@@ -1,16 +1,83 @@
-// Package dat is a parser for dat files, a binary database of games with
-// metadata also used by RetroArch.
+// Package dat is a parser for dat files, a database of games with metadata
+// also used by RetroArch. It reads Logiqx XML and ClrMamePro dats, and the
+// libretro RDB format.
 package dat
 
 import (
 	"encoding/xml"
 	"log"
+	"sort"
 	"strconv"
-	"sync"
+	"strings"
 )
 
-// DB is a database that contains many Dats, mapped to their system name
-type DB map[string]Dat
+// DB is a database that contains many Dats, mapped to their system name. It
+// is indexed to find games without looping over all the Dats.
+type DB struct {
+	Dats  map[string]Dat
+	index index
+
+	// Fingerprint identifies the files the DB was loaded from, games matched
+	// with another fingerprint have to be matched again
+	Fingerprint string
+}
+
+// ref locates a game in a DB
+type ref struct {
+	System string
+	Game   int
+}
+
+// index maps the checksums, serials and ROM names to the games having them,
+// it only considers the first ROM of each game
+type index struct {
+	CRC    map[uint32][]ref
+	MD5    map[string][]ref
+	SHA1   map[string][]ref
+	Serial map[string][]ref
+	Name   map[string][]ref
+}
+
+// NewDB indexes the games of the Dats
+func NewDB(dats map[string]Dat) DB {
+	idx := index{
+		CRC:    map[uint32][]ref{},
+		MD5:    map[string][]ref{},
+		SHA1:   map[string][]ref{},
+		Serial: map[string][]ref{},
+		Name:   map[string][]ref{},
+	}
+	// Index the systems in a stable order, so lookups return games in the
+	// same order every time
+	var systems []string
+	for system := range dats {
+		systems = append(systems, system)
+	}
+	sort.Strings(systems)
+	for _, system := range systems {
+		for i, game := range dats[system].Games {
+			if len(game.ROMs) == 0 {
+				continue
+			}
+			r := ref{system, i}
+			rom := game.ROMs[0]
+			idx.CRC[uint32(rom.CRC)] = append(idx.CRC[uint32(rom.CRC)], r)
+			if rom.MD5 != "" {
+				md5 := strings.ToLower(rom.MD5)
+				idx.MD5[md5] = append(idx.MD5[md5], r)
+			}
+			if rom.SHA1 != "" {
+				sha1 := strings.ToLower(rom.SHA1)
+				idx.SHA1[sha1] = append(idx.SHA1[sha1], r)
+			}
+			if serial := NormalizeSerial(game.GetSerial()); serial != "" {
+				idx.Serial[serial] = append(idx.Serial[serial], r)
+			}
+			idx.Name[rom.Name] = append(idx.Name[rom.Name], r)
+		}
+	}
+	return DB{Dats: dats, index: idx}
+}
 
 // Dat is a list of the games of a system
 type Dat struct {
@@ -23,6 +90,10 @@ type Game struct {
 	XMLName     xml.Name `xml:"game"`
 	Name        string   `xml:"name,attr"`
 	Description string   `xml:"description"` // The human readable name of the game
+	Serial      string   `xml:"serial"`
+	Genre       string   `xml:"genre"`
+	Developer   string   `xml:"developer"`
+	Year        string   `xml:"year"` // Release year
 	ROMs        []ROM    `xml:"rom"`
 
 	Path   string
@@ -32,11 +103,58 @@ type Game struct {
 // CRC is the CRC32 checksum of a ROM
 type CRC uint32
 
-// ROM can be a game file or part of a game
+// ROM can be a game file or part of a game. MD5 and SHA1 are hex strings.
 type ROM struct {
 	XMLName xml.Name `xml:"rom"`
 	Name    string   `xml:"name,attr"`
 	CRC     CRC      `xml:"crc,attr"`
+	MD5     string   `xml:"md5,attr"`
+	SHA1    string   `xml:"sha1,attr"`
+	Serial  string   `xml:"serial,attr"`
+	Status  string   `xml:"status,attr"` // "baddump" for known bad dumps
+}
+
+// Hashes are the checksums of a file, the MD5 and SHA1 can be empty if they
+// are not known
+type Hashes struct {
+	MD5  string
+	SHA1 string
+}
+
+// Matches tells if the hashes of a file are the ones of the ROM. When the
+// database or the file lack a hash, it is not compared.
+func (r ROM) Matches(h Hashes) bool {
+	if r.SHA1 != "" && h.SHA1 != "" {
+		return strings.EqualFold(r.SHA1, h.SHA1)
+	}
+	if r.MD5 != "" && h.MD5 != "" {
+		return strings.EqualFold(r.MD5, h.MD5)
+	}
+	return true
+}
+
+// GetSerial returns the serial of a game, like SLUS-01234, which can be set on
+// the game or on its first ROM
+func (g Game) GetSerial() string {
+	if g.Serial != "" {
+		return g.Serial
+	}
+	if len(g.ROMs) > 0 {
+		return g.ROMs[0].Serial
+	}
+	return ""
+}
+
+// NormalizeSerial removes the punctuation from a serial, so SLUS_012.34 read
+// on a disc matches SLUS-01234 from the database
+func NormalizeSerial(serial string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '.', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(serial))
 }
 
 // UnmarshalXMLAttr is used to parse a hex number in string form to uint
@@ -62,54 +180,95 @@ func Parse(dat []byte) Dat {
 	return output
 }
 
-// FindByCRC loops over the Dats in the DB and concurrently matches CRC checksums.
+// games returns the games located by refs, with their system set
+func (db *DB) games(refs []ref) []Game {
+	var found []Game
+	for _, r := range refs {
+		game := db.Dats[r.System].Games[r.Game]
+		game.System = r.System
+		found = append(found, game)
+	}
+	return found
+}
+
+// ByCRC returns the games whose first ROM has this CRC checksum
+func (db *DB) ByCRC(crc uint32) []Game {
+	return db.games(db.index.CRC[crc])
+}
+
+// ByMD5 returns the games whose first ROM has this MD5 checksum
+func (db *DB) ByMD5(md5 string) []Game {
+	return db.games(db.index.MD5[strings.ToLower(md5)])
+}
+
+// BySHA1 returns the games whose first ROM has this SHA1 checksum
+func (db *DB) BySHA1(sha1 string) []Game {
+	return db.games(db.index.SHA1[strings.ToLower(sha1)])
+}
+
+// BySerial returns the games having this serial, once normalized
+func (db *DB) BySerial(serial string) []Game {
+	return db.games(db.index.Serial[NormalizeSerial(serial)])
+}
+
+// ByROMName returns the games whose first ROM has this file name
+func (db *DB) ByROMName(name string) []Game {
+	return db.games(db.index.Name[name])
+}
+
+// send sends the games found to the channel, with their path set
+func send(found []Game, romPath string, games chan (Game)) int {
+	for _, game := range found {
+		game.Path = romPath
+		games <- game
+	}
+	return len(found)
+}
+
+// FindByCRC finds the games matching a CRC checksum.
 func (db *DB) FindByCRC(romPath string, romName string, crc uint32, games chan (Game)) {
-	var wg sync.WaitGroup
-	wg.Add(len(*db))
-	// For every Dat in the DB
-	for system, dat := range *db {
-		go func(dat Dat, crc uint32, system string) {
-			// For each game in the Dat
-			for _, game := range dat.Games {
-				if len(game.ROMs) == 0 {
-					continue
-				}
-				// If the checksums match
-				if crc == uint32(game.ROMs[0].CRC) {
-					game.Path = romPath
-					game.System = system
-					games <- game
-				}
+	db.FindByHash(romPath, romName, crc, nil, games)
+}
+
+// FindByHash matches CRC checksums, like FindByCRC. When several games share
+// the same CRC, hash is called to get the MD5 and SHA1 of the ROM and keep the
+// games matching them. It returns the number of games found.
+func (db *DB) FindByHash(romPath string, romName string, crc uint32, hash func() (Hashes, error), games chan (Game)) int {
+	found := db.ByCRC(crc)
+
+	if len(found) > 1 && hash != nil {
+		h, err := hash()
+		if err != nil {
+			log.Println(err)
+			return send(found, romPath, games)
+		}
+		var matching []Game
+		for _, game := range found {
+			if game.ROMs[0].Matches(h) {
+				matching = append(matching, game)
 			}
-			wg.Done()
-		}(dat, crc, system)
+		}
+		if len(matching) > 0 {
+			found = matching
+		}
 	}
-	// Synchronize all the goroutines
-	wg.Wait()
+
+	return send(found, romPath, games)
 }
 
-// FindByROMName loops over the Dats in the DB and concurrently matches ROM names.
-func (db *DB) FindByROMName(romPath string, romName string, crc uint32, games chan (Game)) {
-	var wg sync.WaitGroup
-	wg.Add(len(*db))
-	// For every Dat in the DB
-	for system, dat := range *db {
-		go func(dat Dat, crc uint32, system string) {
-			// For each game in the Dat
-			for _, game := range dat.Games {
-				if len(game.ROMs) == 0 {
-					continue
-				}
-				// If the checksums match
-				if romName == game.ROMs[0].Name {
-					game.Path = romPath
-					game.System = system
-					games <- game
-				}
-			}
-			wg.Done()
-		}(dat, crc, system)
+// FindBySerial finds the games matching a serial.
+// It returns the number of games found.
+func (db *DB) FindBySerial(romPath string, serial string, games chan (Game)) int {
+	serial = NormalizeSerial(serial)
+	if serial == "" {
+		return 0
 	}
-	// Synchronize all the goroutines
-	wg.Wait()
+	found := db.BySerial(serial)
+	return send(found, romPath, games)
+}
+
+// FindByROMName finds the games matching a ROM name.
+func (db *DB) FindByROMName(romPath string, romName string, crc uint32, games chan (Game)) {
+	found := db.ByROMName(romName)
+	send(found, romPath, games)
 }
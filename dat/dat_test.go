@@ -0,0 +1,178 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testDat = `<?xml version="1.0"?>
+<datafile>
+	<game name="Game (USA)">
+		<description>Game (USA)</description>
+		<rom name="Game (USA).sfc" crc="1234abcd" md5="0123456789abcdef0123456789abcdef" sha1="aaaa"/>
+	</game>
+	<game name="Game (Europe)">
+		<description>Game (Europe)</description>
+		<rom name="Game (Europe).sfc" crc="1234abcd" md5="fedcba9876543210fedcba9876543210" sha1="BBBB"/>
+	</game>
+	<game name="Disc Game (USA)">
+		<description>Disc Game (USA)</description>
+		<serial>SLUS-01234</serial>
+		<rom name="Disc Game (USA).cue" crc="00000001"/>
+	</game>
+	<game name="Other Disc (Japan)">
+		<description>Other Disc (Japan)</description>
+		<rom name="Other Disc (Japan).cue" crc="00000002" serial="T-4507G"/>
+	</game>
+</datafile>`
+
+func collect(f func(games chan Game)) []Game {
+	games := make(chan Game)
+	go func() {
+		f(games)
+		close(games)
+	}()
+	var found []Game
+	for game := range games {
+		found = append(found, game)
+	}
+	return found
+}
+
+func Test_Parse(t *testing.T) {
+	d := Parse([]byte(testDat))
+	if len(d.Games) != 4 {
+		t.Fatalf("Parse() = %d games, want 4", len(d.Games))
+	}
+	rom := d.Games[1].ROMs[0]
+	if rom.CRC != 0x1234abcd || rom.MD5 != "fedcba9876543210fedcba9876543210" || rom.SHA1 != "BBBB" {
+		t.Errorf("Parse() = %+v", rom)
+	}
+	if d.Games[2].GetSerial() != "SLUS-01234" || d.Games[3].GetSerial() != "T-4507G" {
+		t.Errorf("GetSerial() = %v, %v", d.Games[2].GetSerial(), d.Games[3].GetSerial())
+	}
+}
+
+func Test_FindByHash(t *testing.T) {
+	db := NewDB(map[string]Dat{"Nintendo - SNES": Parse([]byte(testDat))})
+
+	t.Run("Returns all the games sharing a CRC without hashes", func(t *testing.T) {
+		found := collect(func(games chan Game) {
+			db.FindByCRC("/roms/game.sfc", "game.sfc", 0x1234abcd, games)
+		})
+		if len(found) != 2 {
+			t.Errorf("FindByCRC() = %d games, want 2", len(found))
+		}
+	})
+
+	t.Run("Disambiguates by SHA1", func(t *testing.T) {
+		found := collect(func(games chan Game) {
+			db.FindByHash("/roms/game.sfc", "game.sfc", 0x1234abcd, func() (Hashes, error) {
+				return Hashes{MD5: "0123456789abcdef0123456789abcdef", SHA1: "bbbb"}, nil
+			}, games)
+		})
+		if len(found) != 1 || found[0].Name != "Game (Europe)" || found[0].Path != "/roms/game.sfc" {
+			t.Errorf("FindByHash() = %v", found)
+		}
+	})
+
+	t.Run("Doesn't compute hashes for unique CRCs", func(t *testing.T) {
+		found := collect(func(games chan Game) {
+			db.FindByHash("/roms/disc.cue", "disc.cue", 1, func() (Hashes, error) {
+				t.Error("hash should not be called")
+				return Hashes{}, nil
+			}, games)
+		})
+		if len(found) != 1 {
+			t.Errorf("FindByHash() = %d games, want 1", len(found))
+		}
+	})
+}
+
+func Test_FindBySerial(t *testing.T) {
+	db := NewDB(map[string]Dat{"Sony - PlayStation": Parse([]byte(testDat))})
+
+	found := collect(func(games chan Game) {
+		db.FindBySerial("/roms/renamed.cue", "SLUS_012.34", games)
+	})
+	if len(found) != 1 || found[0].Name != "Disc Game (USA)" || found[0].System != "Sony - PlayStation" {
+		t.Errorf("FindBySerial() = %v", found)
+	}
+
+	found = collect(func(games chan Game) {
+		db.FindBySerial("/roms/saturn.cue", "T-4507G", games)
+	})
+	if len(found) != 1 {
+		t.Errorf("FindBySerial() = %v", found)
+	}
+}
+
+func Test_Index(t *testing.T) {
+	db := NewDB(map[string]Dat{"Nintendo - SNES": Parse([]byte(testDat))})
+
+	if found := db.ByCRC(0x1234abcd); len(found) != 2 || found[0].System != "Nintendo - SNES" {
+		t.Errorf("ByCRC() = %v", found)
+	}
+	if found := db.ByMD5("FEDCBA9876543210FEDCBA9876543210"); len(found) != 1 || found[0].Name != "Game (Europe)" {
+		t.Errorf("ByMD5() = %v", found)
+	}
+	if found := db.BySHA1("bbbb"); len(found) != 1 || found[0].Name != "Game (Europe)" {
+		t.Errorf("BySHA1() = %v", found)
+	}
+	if found := db.BySerial("SLUS_012.34"); len(found) != 1 || found[0].Name != "Disc Game (USA)" {
+		t.Errorf("BySerial() = %v", found)
+	}
+	if found := db.ByCRC(0xdeadbeef); len(found) != 0 {
+		t.Errorf("ByCRC() = %v", found)
+	}
+}
+
+func Test_Cache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dat.gob")
+
+	db := NewDB(map[string]Dat{"Nintendo - SNES": Parse([]byte(testDat))})
+	sources := Sources{"/dats/Nintendo - SNES.dat": {Size: 42, ModTime: time.Unix(1000, 0)}}
+	if err := db.WriteCache(path, sources); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Reads the cached DB", func(t *testing.T) {
+		cached, err := ReadCache(path, Sources{"/dats/Nintendo - SNES.dat": {Size: 42, ModTime: time.Unix(1000, 0)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cached.ByCRC(0x1234abcd), db.ByCRC(0x1234abcd)) {
+			t.Errorf("ByCRC() = %v, want %v", cached.ByCRC(0x1234abcd), db.ByCRC(0x1234abcd))
+		}
+	})
+
+	t.Run("Rejects an outdated cache", func(t *testing.T) {
+		if _, err := ReadCache(path, Sources{"/dats/Nintendo - SNES.dat": {Size: 43, ModTime: time.Unix(1000, 0)}}); err == nil {
+			t.Error("ReadCache() should fail")
+		}
+		if _, err := ReadCache(path, Sources{}); err == nil {
+			t.Error("ReadCache() should fail")
+		}
+	})
+
+	t.Run("Rejects a cache written by another version", func(t *testing.T) {
+		old := filepath.Join(dir, "old.gob")
+		var buf bytes.Buffer
+		gob.NewEncoder(&buf).Encode(cache{cacheVersion - 1, sources, db.Dats, db.index})
+		ioutil.WriteFile(old, buf.Bytes(), 0644)
+		if _, err := ReadCache(old, sources); err == nil {
+			t.Error("ReadCache() should fail")
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package dat
+
+import "fmt"
+
+// mergeKey returns what identifies a game across the databases of a system:
+// the CRC of its first ROM, or its serial or name when the CRC is unknown
+func mergeKey(g Game) string {
+	if len(g.ROMs) > 0 && g.ROMs[0].CRC != 0 {
+		return fmt.Sprintf("crc:%08x", uint32(g.ROMs[0].CRC))
+	}
+	if serial := NormalizeSerial(g.GetSerial()); serial != "" {
+		return "serial:" + serial
+	}
+	return "name:" + g.Name
+}
+
+// fill sets the empty fields of a game from another version of the same game
+func fill(g *Game, o Game) {
+	str := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	str(&g.Name, o.Name)
+	str(&g.Description, o.Description)
+	str(&g.Serial, o.Serial)
+	str(&g.Genre, o.Genre)
+	str(&g.Developer, o.Developer)
+	str(&g.Year, o.Year)
+	if len(o.ROMs) == 0 {
+		return
+	}
+	if len(g.ROMs) == 0 {
+		g.ROMs = append([]ROM{}, o.ROMs...)
+		return
+	}
+	r, or := &g.ROMs[0], o.ROMs[0]
+	str(&r.Name, or.Name)
+	str(&r.MD5, or.MD5)
+	str(&r.SHA1, or.SHA1)
+	str(&r.Serial, or.Serial)
+	str(&r.Status, or.Status)
+	if r.CRC == 0 {
+		r.CRC = or.CRC
+	}
+}
+
+// compatible tells if two games with the same key can be the same game, their
+// hashes must not differ
+func compatible(g, o Game) bool {
+	if len(g.ROMs) == 0 || len(o.ROMs) == 0 {
+		return true
+	}
+	return g.ROMs[0].Matches(Hashes{MD5: o.ROMs[0].MD5, SHA1: o.ROMs[0].SHA1})
+}
+
+// Merge joins the dats of a system, like the dat, RDB and metadat files of the
+// libretro database. A game is merged with a game of a previous dat having the
+// same CRC, or the same serial or name when the CRC is unknown, and compatible
+// hashes. Merged games keep the first non empty value of each field. Games
+// without ROM name nor serial only bring metadata, they are dropped if they
+// match no other game.
+func Merge(dats ...Dat) Dat {
+	var merged Dat
+	keys := map[string][]int{}
+	for _, d := range dats {
+		// Games of the same dat are never merged together, different games
+		// can share a CRC
+		taken := map[int]bool{}
+		for _, g := range d.Games {
+			key := mergeKey(g)
+			i := -1
+			for _, j := range keys[key] {
+				if !taken[j] && compatible(merged.Games[j], g) {
+					i = j
+					break
+				}
+			}
+			if i >= 0 {
+				fill(&merged.Games[i], g)
+				taken[i] = true
+				continue
+			}
+			i = len(merged.Games)
+			keys[key] = append(keys[key], i)
+			taken[i] = true
+			g.ROMs = append([]ROM{}, g.ROMs...) // fill must not modify d
+			merged.Games = append(merged.Games, g)
+		}
+	}
+
+	games := merged.Games[:0]
+	for _, g := range merged.Games {
+		if g.GetSerial() != "" || (len(g.ROMs) > 0 && g.ROMs[0].Name != "") {
+			games = append(games, g)
+		}
+	}
+	merged.Games = games
+	return merged
+}
@@ -0,0 +1,37 @@
+package dat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Merge(t *testing.T) {
+	dat := Dat{Games: []Game{
+		{Name: "Game (USA)", Description: "Game (USA)", ROMs: []ROM{{Name: "Game (USA).sfc", CRC: 1, MD5: "aa"}}},
+		{Name: "Game (Europe)", Description: "Game (Europe)", ROMs: []ROM{{Name: "Game (Europe).sfc", CRC: 1, MD5: "bb"}}},
+		{Name: "Disc (USA)", ROMs: []ROM{{Name: "Disc (USA).cue", Serial: "SLUS-01234"}}},
+	}}
+	rdb := Dat{Games: []Game{
+		{Name: "Game (Europe)", Genre: "Action", ROMs: []ROM{{Name: "Game (Europe).sfc", CRC: 1, MD5: "BB"}}},
+		{Name: "Disc (USA)", Developer: "Studio", Serial: "SLUS_012.34"},
+		{Name: "Other (Japan)", ROMs: []ROM{{Name: "Other (Japan).sfc", CRC: 2}}},
+	}}
+	metadat := Dat{Games: []Game{
+		{Name: "Game (USA)", Year: "1994", ROMs: []ROM{{CRC: 1, MD5: "aa"}}},
+		{Name: "Unknown", Genre: "Puzzle", ROMs: []ROM{{CRC: 3}}},
+	}}
+
+	want := Dat{Games: []Game{
+		{Name: "Game (USA)", Description: "Game (USA)", Year: "1994", ROMs: []ROM{{Name: "Game (USA).sfc", CRC: 1, MD5: "aa"}}},
+		{Name: "Game (Europe)", Description: "Game (Europe)", Genre: "Action", ROMs: []ROM{{Name: "Game (Europe).sfc", CRC: 1, MD5: "bb"}}},
+		{Name: "Disc (USA)", Developer: "Studio", Serial: "SLUS_012.34", ROMs: []ROM{{Name: "Disc (USA).cue", Serial: "SLUS-01234"}}},
+		{Name: "Other (Japan)", ROMs: []ROM{{Name: "Other (Japan).sfc", CRC: 2}}},
+	}}
+	got := Merge(dat, rdb, metadat)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+	if dat.Games[0].Year != "" || dat.Games[0].ROMs[0].MD5 != "aa" {
+		t.Errorf("Merge() modified its arguments")
+	}
+}
@@ -0,0 +1,159 @@
+package dat
+
+import (
+	"errors"
+	"math"
+)
+
+// msgpack decodes the subset of MessagePack used by libretro RDB files. Maps
+// are decoded to map[string]interface{}, strings to string, binaries to
+// []byte and numbers to int64, uint64 or float64.
+type msgpack struct {
+	data   []byte
+	offset int
+}
+
+var errMsgpack = errors.New("invalid msgpack data")
+
+func (m *msgpack) bytes(n int) ([]byte, error) {
+	if n < 0 || m.offset+n > len(m.data) {
+		return nil, errMsgpack
+	}
+	b := m.data[m.offset : m.offset+n]
+	m.offset += n
+	return b, nil
+}
+
+// uint reads a big endian unsigned number of n bytes
+func (m *msgpack) uint(n int) (uint64, error) {
+	b, err := m.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// length reads a length of n bytes, and makes sure that much data is left
+func (m *msgpack) length(n int) (int, error) {
+	u, err := m.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	if u > uint64(len(m.data)-m.offset) {
+		return 0, errMsgpack
+	}
+	return int(u), nil
+}
+
+// value decodes the next value
+func (m *msgpack) value() (interface{}, error) {
+	t, err := m.uint(1)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t <= 0x7f:
+		return uint64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return m.mapOf(int(t & 0x0f))
+	case t&0xf0 == 0x90:
+		return m.arrayOf(int(t & 0x0f))
+	case t&0xe0 == 0xa0:
+		return m.str(int(t & 0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := m.length(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := m.bytes(n)
+		return append([]byte{}, b...), err
+	case 0xca:
+		u, err := m.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := m.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return m.uint(1 << (t - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		u, err := m.uint(size)
+		// Sign extend the number
+		shift := uint(64 - 8*size)
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := m.length(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return m.str(n)
+	case 0xdc, 0xdd:
+		n, err := m.length(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return m.arrayOf(n)
+	case 0xde, 0xdf:
+		n, err := m.length(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return m.mapOf(n)
+	}
+	return nil, errMsgpack
+}
+
+func (m *msgpack) str(n int) (string, error) {
+	b, err := m.bytes(n)
+	return string(b), err
+}
+
+func (m *msgpack) arrayOf(n int) ([]interface{}, error) {
+	var a []interface{}
+	for i := 0; i < n; i++ {
+		v, err := m.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func (m *msgpack) mapOf(n int) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		k, err := m.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := m.value()
+		if err != nil {
+			return nil, err
+		}
+		switch k := k.(type) {
+		case string:
+			out[k] = v
+		case []byte:
+			out[string(k)] = v
+		default:
+			return nil, errMsgpack
+		}
+	}
+	return out, nil
+}
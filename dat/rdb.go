@@ -0,0 +1,91 @@
+package dat
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// RDB files start with a 16 bytes header: a magic number and the offset of
+// the metadata. The entries follow, as a list of MessagePack maps terminated
+// by a nil value.
+const (
+	rdbMagic      = "RARCHDB"
+	rdbHeaderSize = 16
+)
+
+// ParseRDB parses the content of a libretro .rdb file
+func ParseRDB(rdb []byte) (Dat, error) {
+	var output Dat
+	if len(rdb) < rdbHeaderSize || string(rdb[:len(rdbMagic)]) != rdbMagic {
+		return output, errors.New("invalid rdb header")
+	}
+
+	end := len(rdb)
+	if offset := binary.BigEndian.Uint64(rdb[8:]); offset >= rdbHeaderSize && offset < uint64(end) {
+		end = int(offset)
+	}
+
+	m := &msgpack{data: rdb[:end], offset: rdbHeaderSize}
+	for m.offset < end {
+		v, err := m.value()
+		if err != nil {
+			return output, err
+		}
+		if v == nil {
+			break
+		}
+		entry, ok := v.(map[string]interface{})
+		if !ok {
+			return output, errMsgpack
+		}
+		output.Games = append(output.Games, rdbGame(entry))
+	}
+	return output, nil
+}
+
+// rdbGame converts an RDB entry to a game with a single ROM
+func rdbGame(entry map[string]interface{}) Game {
+	str := func(key string) string {
+		switch v := entry[key].(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		case uint64, int64:
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	hash := func(key string) string {
+		if v, ok := entry[key].([]byte); ok {
+			return hex.EncodeToString(v)
+		}
+		return str(key)
+	}
+
+	game := Game{
+		Name:        str("name"),
+		Description: str("description"),
+		Serial:      str("serial"),
+		Genre:       str("genre"),
+		Developer:   str("developer"),
+		Year:        str("releaseyear"),
+	}
+	// The description is what is displayed in the playlists, RDBs only have
+	// it for some systems
+	if game.Description == "" {
+		game.Description = game.Name
+	}
+	rom := ROM{
+		Name: str("rom_name"),
+		MD5:  hash("md5"),
+		SHA1: hash("sha1"),
+	}
+	if crc, ok := entry["crc"].([]byte); ok && len(crc) == 4 {
+		rom.CRC = CRC(binary.BigEndian.Uint32(crc))
+	}
+	game.ROMs = []ROM{rom}
+	return game
+}
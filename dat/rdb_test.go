@@ -0,0 +1,96 @@
+package dat
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// msgpackStr encodes a string with a str8 header
+func msgpackStr(s string) []byte {
+	return append([]byte{0xd9, byte(len(s))}, s...)
+}
+
+// msgpackBin encodes a binary with a bin8 header
+func msgpackBin(b []byte) []byte {
+	return append([]byte{0xc4, byte(len(b))}, b...)
+}
+
+// testRDB builds an RDB containing one entry
+func testRDB() []byte {
+	entry := []byte{0x87} // fixmap of 7 pairs
+	entry = append(entry, msgpackStr("name")...)
+	entry = append(entry, msgpackStr("Game (USA)")...)
+	entry = append(entry, msgpackStr("rom_name")...)
+	entry = append(entry, msgpackStr("Game (USA).sfc")...)
+	entry = append(entry, msgpackStr("genre")...)
+	entry = append(entry, msgpackStr("Platform")...)
+	entry = append(entry, msgpackStr("developer")...)
+	entry = append(entry, msgpackStr("Studio")...)
+	entry = append(entry, msgpackStr("releaseyear")...)
+	entry = append(entry, 0xcd, 0x07, 0xcf) // uint16 1999
+	entry = append(entry, msgpackStr("crc")...)
+	entry = append(entry, msgpackBin([]byte{0x12, 0x34, 0xab, 0xcd})...)
+	entry = append(entry, msgpackStr("serial")...)
+	entry = append(entry, msgpackBin([]byte("SNS-XX-USA"))...)
+
+	rdb := append([]byte("RARCHDB\x00"), make([]byte, 8)...)
+	rdb = append(rdb, entry...)
+	rdb = append(rdb, 0xc0) // end of the entries
+	binary.BigEndian.PutUint64(rdb[8:], uint64(len(rdb)))
+	rdb = append(rdb, 0x81) // metadata
+	rdb = append(rdb, msgpackStr("count")...)
+	rdb = append(rdb, 0x01)
+	return rdb
+}
+
+func Test_ParseRDB(t *testing.T) {
+	d, err := ParseRDB(testRDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Game{{
+		Name:        "Game (USA)",
+		Description: "Game (USA)",
+		Serial:      "SNS-XX-USA",
+		Genre:       "Platform",
+		Developer:   "Studio",
+		Year:        "1999",
+		ROMs:        []ROM{{Name: "Game (USA).sfc", CRC: 0x1234abcd}},
+	}}
+	if !reflect.DeepEqual(d.Games, want) {
+		t.Errorf("ParseRDB() = %+v, want %+v", d.Games, want)
+	}
+
+	if _, err := ParseRDB([]byte("NOTARDB\x00\x00\x00\x00\x00\x00\x00\x00\x00")); err == nil {
+		t.Error("ParseRDB() should fail on an invalid header")
+	}
+	truncated := testRDB()[:30]
+	binary.BigEndian.PutUint64(truncated[8:], 0)
+	if _, err := ParseRDB(truncated); err == nil {
+		t.Error("ParseRDB() should fail on truncated data")
+	}
+}
+
+func Test_msgpack(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want interface{}
+	}{
+		{[]byte{0x05}, uint64(5)},
+		{[]byte{0xff}, int64(-1)},
+		{[]byte{0xd1, 0xff, 0x00}, int64(-256)},
+		{[]byte{0xce, 0x00, 0x01, 0x00, 0x00}, uint64(65536)},
+		{[]byte{0xa3, 'a', 'b', 'c'}, "abc"},
+		{[]byte{0xc3}, true},
+		{[]byte{0x92, 0x01, 0xa1, 'x'}, []interface{}{uint64(1), "x"}},
+		{[]byte{0x81, 0xa1, 'k', 0xc0}, map[string]interface{}{"k": nil}},
+	}
+	for _, tt := range tests {
+		m := &msgpack{data: tt.data}
+		got, err := m.value()
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("value(%x) = %#v, %v, want %#v", tt.data, got, err, tt.want)
+		}
+	}
+}
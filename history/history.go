@@ -27,8 +27,22 @@ type History []Game
 // List is the list of recently played games
 var List History
 
-// Push pushes a game onto the stack
+// Find returns the history entry of a game
+func Find(path string) (Game, bool) {
+	for _, g := range List {
+		if g.Path == path {
+			return g, true
+		}
+	}
+	return Game{}, false
+}
+
+// Push pushes a game onto the stack. The savestate of the game is kept if the
+// game was already in the history.
 func Push(g Game) {
+	if old, ok := Find(g.Path); ok && g.Savestate == "" {
+		g.Savestate = old.Savestate
+	}
 	List = append([]Game{g}, List...)
 
 	// Deduplicate
@@ -57,6 +71,7 @@ func Load() error {
 	defer file.Close()
 
 	wr := csv.NewReader(bufio.NewReader(file))
+	wr.FieldsPerRecord = -1 // older history files have no savestate column
 
 	List = History{}
 	for {
@@ -67,12 +82,19 @@ func Load() error {
 		if err != nil {
 			return err
 		}
-		List = append(List, Game{
+		if len(record) < 4 {
+			continue
+		}
+		game := Game{
 			Path:     record[0],
 			Name:     record[1],
 			System:   record[2],
 			CorePath: record[3],
-		})
+		}
+		if len(record) > 4 {
+			game.Savestate = record[4]
+		}
+		List = append(List, game)
 	}
 
 	return nil
@@ -95,8 +117,21 @@ func Save() error {
 			game.Name,
 			game.System,
 			game.CorePath,
+			game.Savestate,
 		})
 	}
 
 	return nil
 }
+
+// SetSavestate remembers the last savestate of a game. It does nothing if the
+// game is not in the history.
+func SetSavestate(gamePath, savestatePath string) error {
+	for i := range List {
+		if List[i].Path == gamePath {
+			List[i].Savestate = savestatePath
+			return Save()
+		}
+	}
+	return nil
+}
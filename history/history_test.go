@@ -0,0 +1,75 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+func withDataHome(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "ludo"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	old := xdg.DataHome
+	xdg.DataHome = dir
+	return func() {
+		xdg.DataHome = old
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_SaveLoad(t *testing.T) {
+	defer withDataHome(t)()
+
+	List = History{}
+	Push(Game{Path: "/roms/a.sfc", Name: "A", System: "Nintendo - SNES", CorePath: "/cores/snes.so"})
+	if err := SetSavestate("/roms/a.sfc", "/states/a@auto.state"); err != nil {
+		t.Fatal(err)
+	}
+	want := List
+
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(List, want) {
+		t.Errorf("Load() = %v, want %v", List, want)
+	}
+}
+
+func Test_Load_withoutSavestateColumn(t *testing.T) {
+	defer withDataHome(t)()
+
+	csv := "/roms/a.sfc,A,Nintendo - SNES,/cores/snes.so\n"
+	err := ioutil.WriteFile(filepath.Join(xdg.DataHome, "ludo", "history.csv"), []byte(csv), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	want := History{{Path: "/roms/a.sfc", Name: "A", System: "Nintendo - SNES", CorePath: "/cores/snes.so"}}
+	if !reflect.DeepEqual(List, want) {
+		t.Errorf("Load() = %v, want %v", List, want)
+	}
+}
+
+func Test_Push_keepsSavestate(t *testing.T) {
+	defer withDataHome(t)()
+
+	List = History{{Path: "/roms/a.sfc", Savestate: "/states/a@auto.state"}}
+	Push(Game{Path: "/roms/b.sfc"})
+	Push(Game{Path: "/roms/a.sfc"})
+
+	if List[0].Path != "/roms/a.sfc" || List[0].Savestate != "/states/a@auto.state" {
+		t.Errorf("Push() = %v, want the savestate to be kept", List[0])
+	}
+}
@@ -26,3 +26,23 @@ var joyBinds = map[glfw.GamepadButton]uint32{
 	glfw.ButtonBack:  libretro.DeviceIDJoypadSelect,
 	glfw.ButtonGuide: ActionMenuToggle,
 }
+
+// HotkeyButtons lists the buttons that can be held to trigger the hotkeys, Off
+// disables the hotkeys
+var HotkeyButtons = []string{"Off", "Select", "L3", "R3"}
+
+// hotkeyButtons maps the names of HotkeyButtons to the joypad buttons
+var hotkeyButtons = map[string]glfw.GamepadButton{
+	"Select": glfw.ButtonBack,
+	"L3":     glfw.ButtonLeftThumb,
+	"R3":     glfw.ButtonRightThumb,
+}
+
+// joyHotkeys are triggered by pressing a button while holding the hotkey
+// button
+var joyHotkeys = map[glfw.GamepadButton]uint32{
+	glfw.ButtonRightBumper: ActionSaveState,
+	glfw.ButtonLeftBumper:  ActionLoadState,
+	glfw.ButtonDpadRight:   ActionNextSlot,
+	glfw.ButtonDpadLeft:    ActionPrevSlot,
+}
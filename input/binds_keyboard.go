@@ -22,4 +22,8 @@ var keyBinds = map[glfw.Key]uint32{
 	glfw.KeyP:          ActionMenuToggle,
 	glfw.KeyF:          ActionFullscreenToggle,
 	glfw.KeyEscape:     ActionShouldClose,
+	glfw.KeyF2:         ActionSaveState,
+	glfw.KeyF4:         ActionLoadState,
+	glfw.KeyF6:         ActionPrevSlot,
+	glfw.KeyF7:         ActionNextSlot,
 }
@@ -30,6 +30,11 @@ var (
 	Pressed  States // keys just pressed during this frame
 
 	NewAnalogState AnalogStates // analog input state for the current frame
+
+	// SelectCombo is true for the players who pressed another key while
+	// holding select, like a hotkey, since select was last pressed. The
+	// release of select is then not a press of select alone.
+	SelectCombo [MaxPlayers]bool
 )
 
 var oldMouseX float64
@@ -45,10 +50,39 @@ const (
 	ActionShouldClose uint32 = lr.DeviceIDJoypadR3 + 3
 	// ActionFastForwardToggle will run the core as fast as possible
 	ActionFastForwardToggle uint32 = lr.DeviceIDJoypadR3 + 4
+	// ActionSaveState saves the state in the current slot
+	ActionSaveState uint32 = lr.DeviceIDJoypadR3 + 5
+	// ActionLoadState loads the state of the current slot
+	ActionLoadState uint32 = lr.DeviceIDJoypadR3 + 6
+	// ActionNextSlot selects the next savestate slot
+	ActionNextSlot uint32 = lr.DeviceIDJoypadR3 + 7
+	// ActionPrevSlot selects the previous savestate slot
+	ActionPrevSlot uint32 = lr.DeviceIDJoypadR3 + 8
 	// ActionLast is used for iterating
-	ActionLast uint32 = lr.DeviceIDJoypadR3 + 5
+	ActionLast uint32 = lr.DeviceIDJoypadR3 + 9
 )
 
+// Buttons maps human readable button names to joypad ids. It is used by
+// scripts and remote commands.
+var Buttons = map[string]uint32{
+	"b":      lr.DeviceIDJoypadB,
+	"y":      lr.DeviceIDJoypadY,
+	"select": lr.DeviceIDJoypadSelect,
+	"start":  lr.DeviceIDJoypadStart,
+	"up":     lr.DeviceIDJoypadUp,
+	"down":   lr.DeviceIDJoypadDown,
+	"left":   lr.DeviceIDJoypadLeft,
+	"right":  lr.DeviceIDJoypadRight,
+	"a":      lr.DeviceIDJoypadA,
+	"x":      lr.DeviceIDJoypadX,
+	"l":      lr.DeviceIDJoypadL,
+	"r":      lr.DeviceIDJoypadR,
+	"l2":     lr.DeviceIDJoypadL2,
+	"r2":     lr.DeviceIDJoypadR2,
+	"l3":     lr.DeviceIDJoypadL3,
+	"r3":     lr.DeviceIDJoypadR3,
+}
+
 // joystickCallback is triggered when a joypad is plugged.
 func joystickCallback(joy glfw.Joystick, event glfw.PeripheralEvent) {
 	switch event {
@@ -80,6 +114,31 @@ func floatToAnalog(v float32) int16 {
 	return int16(v * 32767.0)
 }
 
+// mapJoyButtons maps the buttons of the joypad of a player. Hotkeys are
+// triggered by holding the hotkey button, one of HotkeyButtons, the buttons
+// used as hotkeys are then not passed to the core. All the buttons are passed
+// when the hotkeys are Off.
+func mapJoyButtons(state States, p int, buttons [15]glfw.Action, hotkey string) States {
+	modifier, enabled := hotkeyButtons[hotkey]
+	hotkeys := enabled && buttons[modifier] == glfw.Press
+	for k, v := range joyBinds {
+		if _, ok := joyHotkeys[k]; ok && hotkeys {
+			continue
+		}
+		if buttons[k] == glfw.Press {
+			state[p][v] = 1
+		}
+	}
+	if hotkeys {
+		for k, v := range joyHotkeys {
+			if buttons[k] == glfw.Press {
+				state[p][v] = 1
+			}
+		}
+	}
+	return state
+}
+
 // pollJoypads process joypads of all players
 func pollJoypads(state States, analogState AnalogStates) (States, AnalogStates) {
 	p := 0
@@ -92,12 +151,7 @@ func pollJoypads(state States, analogState AnalogStates) (States, AnalogStates)
 			continue
 		}
 
-		// mapping pad buttons
-		for k, v := range joyBinds {
-			if pad.Buttons[k] == glfw.Press {
-				state[p][v] = 1
-			}
-		}
+		state = mapJoyButtons(state, p, pad.Buttons, settings.Current.HotkeyButton)
 
 		// mapping pad triggers
 		if pad.Axes[glfw.AxisLeftTrigger] > 0.5 {
@@ -161,12 +215,32 @@ func getPressedReleased(new States, old States) (States, States) {
 	return Pressed, Released
 }
 
+// selectCombos tells for each player if another key was pressed while select
+// was held, since select was last pressed
+func selectCombos(combo [MaxPlayers]bool, new, pressed States) [MaxPlayers]bool {
+	for p := range new {
+		if pressed[p][lr.DeviceIDJoypadSelect] == 1 {
+			combo[p] = false
+		}
+		if new[p][lr.DeviceIDJoypadSelect] == 0 {
+			continue
+		}
+		for k, v := range new[p] {
+			if uint32(k) != lr.DeviceIDJoypadSelect && v == 1 {
+				combo[p] = true
+			}
+		}
+	}
+	return combo
+}
+
 // Poll calculates the input state. It is meant to be called for each frame.
 func Poll() {
 	NewState = States{}
 	NewState, NewAnalogState = pollJoypads(NewState, NewAnalogState)
 	NewState = pollKeyboard(NewState)
 	Pressed, Released = getPressedReleased(NewState, OldState)
+	SelectCombo = selectCombos(SelectCombo, NewState, Pressed)
 
 	// Store the old input state for comparisions
 	OldState = NewState
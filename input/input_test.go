@@ -2,6 +2,9 @@ package input
 
 import (
 	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	lr "github.com/libretro/ludo/libretro"
 )
 
 func Test_getPressedReleased(t *testing.T) {
@@ -23,3 +26,66 @@ func Test_getPressedReleased(t *testing.T) {
 		}
 	})
 }
+
+func Test_mapJoyButtons(t *testing.T) {
+	var buttons [15]glfw.Action
+	buttons[glfw.ButtonRightBumper] = glfw.Press
+	buttons[glfw.ButtonDpadUp] = glfw.Press
+
+	t.Run("Passes the buttons to the core", func(t *testing.T) {
+		state := mapJoyButtons(States{}, 1, buttons, "Select")
+		if state[1][lr.DeviceIDJoypadR] != 1 || state[1][lr.DeviceIDJoypadUp] != 1 || state[1][ActionSaveState] != 0 {
+			t.Errorf("got = %v", state[1])
+		}
+	})
+
+	buttons[glfw.ButtonBack] = glfw.Press
+
+	t.Run("Triggers hotkeys instead while the hotkey button is held", func(t *testing.T) {
+		state := mapJoyButtons(States{}, 1, buttons, "Select")
+		if state[1][lr.DeviceIDJoypadR] != 0 || state[1][ActionSaveState] != 1 {
+			t.Errorf("got = %v", state[1])
+		}
+		if state[1][lr.DeviceIDJoypadSelect] != 1 || state[1][lr.DeviceIDJoypadUp] != 1 {
+			t.Errorf("got = %v, want select and up passed to the core", state[1])
+		}
+	})
+
+	t.Run("Passes select combos to the core when hotkeys are off", func(t *testing.T) {
+		for _, hotkey := range []string{"Off", "L3", ""} {
+			state := mapJoyButtons(States{}, 1, buttons, hotkey)
+			if state[1][lr.DeviceIDJoypadR] != 1 || state[1][lr.DeviceIDJoypadSelect] != 1 || state[1][ActionSaveState] != 0 {
+				t.Errorf("%s: got = %v", hotkey, state[1])
+			}
+		}
+	})
+}
+
+func Test_selectCombos(t *testing.T) {
+	var combo [MaxPlayers]bool
+	var state States
+
+	state[0][lr.DeviceIDJoypadSelect] = 1
+	combo = selectCombos(combo, state, state)
+	if combo[0] {
+		t.Errorf("got = %v, want false while select is held alone", combo[0])
+	}
+
+	state[0][ActionSaveState] = 1
+	combo = selectCombos(combo, state, States{})
+	state[0][ActionSaveState] = 0
+	combo = selectCombos(combo, state, States{})
+	if !combo[0] {
+		t.Errorf("got = %v, want true after a hotkey", combo[0])
+	}
+
+	combo = selectCombos(combo, States{}, States{})
+	if !combo[0] {
+		t.Errorf("got = %v, want true when select is released", combo[0])
+	}
+
+	combo = selectCombos(combo, state, state)
+	if combo[0] {
+		t.Errorf("got = %v, want false when select is pressed again", combo[0])
+	}
+}
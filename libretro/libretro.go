@@ -581,9 +581,8 @@ func (core *Core) Unserialize(bytes []byte, size uint) error {
 	if size == 0 || len(bytes) == 0 {
 		return errors.New("retro_unserialize failed")
 	}
-	if uint(len(bytes)) > size {
-		size = uint(len(bytes))
-	}
+	// Never let the core read past the end of the buffer
+	size = uint(len(bytes))
 	ok := bool(C.bridge_retro_unserialize(core.symRetroUnserialize, unsafe.Pointer(&bytes[0]), C.size_t(size)))
 	if !ok {
 		return errors.New("retro_unserialize failed")
@@ -812,7 +811,8 @@ func GetMemoryMap(data unsafe.Pointer) []MemoryDescriptor {
 		descriptors[i] = MemoryDescriptor{
 			Flags:      uint64(d.flags),
 			Ptr:        d.ptr,
-			Offset:     uintptr(d.start),
+			Offset:     uintptr(d.offset),
+			Start:      uintptr(d.start),
 			Select:     uintptr(d._select),
 			Disconnect: uintptr(d.disconnect),
 			Len:        uintptr(d.len),
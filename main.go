@@ -10,6 +10,8 @@ import (
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/collections"
+	"github.com/libretro/ludo/control"
 	"github.com/libretro/ludo/core"
 	"github.com/libretro/ludo/history"
 	"github.com/libretro/ludo/input"
@@ -18,9 +20,12 @@ import (
 	"github.com/libretro/ludo/playlists"
 	"github.com/libretro/ludo/savefiles"
 	"github.com/libretro/ludo/scanner"
+	"github.com/libretro/ludo/scripting"
 	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
 	"github.com/libretro/ludo/video"
+	"github.com/libretro/ludo/watcher"
 )
 
 func init() {
@@ -42,8 +47,10 @@ func runLoop(vid *video.Video, m *menu.Menu) {
 		vid.ResizeViewport()
 		m.UpdatePalette()
 		input.Poll()
+		control.Process()
 		if !state.MenuActive {
 			if state.CoreRunning {
+				scripting.Update()
 				state.Core.Run()
 				if state.Core.FrameTimeCallback != nil {
 					state.Core.FrameTimeCallback.Callback(state.Core.FrameTimeCallback.Reference)
@@ -53,6 +60,7 @@ func runLoop(vid *video.Video, m *menu.Menu) {
 				}
 			}
 			vid.Render()
+			scripting.Render(vid)
 			frame++
 			if frame%600 == 0 { // save sram about every 10 sec
 				savefiles.SaveSRAM()
@@ -74,12 +82,28 @@ func runLoop(vid *video.Video, m *menu.Menu) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "patch" {
+		if err := runPatch(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	err := settings.Load()
 	if err != nil {
 		log.Println("[Settings]: Loading failed:", err)
 		log.Println("[Settings]: Using default settings")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := runAudit(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// ExitOnError causes flags to quit after displaying help.
 	// (--help counts as an error)
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -87,6 +111,8 @@ func main() {
 	// customize help message
 	flag.CommandLine.Usage = func() {
 		fmt.Printf("Usage: %s [OPTIONS] [content]\n", os.Args[0])
+		fmt.Printf("       %s patch create|apply|info ...\n", os.Args[0])
+		fmt.Printf("       %s audit [--format csv|json] [--1g1r] ...\n", os.Args[0])
 		fmt.Printf("Options:\n")
 		flag.PrintDefaults()
 	}
@@ -117,6 +143,8 @@ func main() {
 
 	history.Load()
 
+	collections.Load()
+
 	vid := video.Init(settings.Current.VideoFullscreen)
 
 	audio.Init()
@@ -127,6 +155,27 @@ func main() {
 
 	input.Init(vid)
 
+	control.Init(vid, m.WarpToQuickMenu)
+	if settings.Current.ControlServer {
+		if err := control.Start(); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Control", err.Error())
+		}
+	}
+	if settings.Current.NetworkCommands {
+		if err := control.StartNetworkCommands(); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Control", err.Error())
+		}
+	}
+
+	watcher.Init(func(dir string) {
+		scanner.ScanDir(dir, m.RefreshTabs)
+	})
+	if settings.Current.WatchDirectories {
+		if err := watcher.Start(); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Watcher", err.Error())
+		}
+	}
+
 	if len(state.CorePath) > 0 {
 		err := core.Load(state.CorePath)
 		if err == nil {
@@ -135,6 +184,11 @@ func main() {
 				if err != nil {
 					ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
 				} else {
+					history.Push(history.Game{
+						Path:     gamePath,
+						Name:     utils.FileName(gamePath),
+						CorePath: state.CorePath,
+					})
 					m.WarpToQuickMenu()
 				}
 			}
@@ -145,9 +199,17 @@ func main() {
 
 	// No game running? display the menu
 	state.MenuActive = !state.CoreRunning
+	if state.CoreRunning {
+		m.OfferResume(gamePath)
+	}
 
 	runLoop(vid, m)
 
+	control.Stop()
+	control.StopNetworkCommands()
+	watcher.Stop()
+
 	// Unload and deinit in the core.
 	core.Unload()
+	core.WaitSync()
 }
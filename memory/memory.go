@@ -0,0 +1,155 @@
+// Package memory gives access to the memory regions exposed by the running
+// libretro core, like the system RAM or the save RAM. It is used by scripts
+// and remote commands to peek and poke the game memory.
+package memory
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/state"
+)
+
+// region returns the memory region of the given id as a go slice pointing to
+// the core memory. Writing to this slice writes to the core memory.
+func region(id uint32) ([]byte, error) {
+	if !state.CoreRunning {
+		return nil, errors.New("core not running")
+	}
+
+	len := state.Core.GetMemorySize(id)
+	ptr := state.Core.GetMemoryData(id)
+	if ptr == nil || len == 0 {
+		return nil, errors.New("unable to get memory address")
+	}
+
+	// this *[1 << 30]byte points to the same memory as ptr
+	return (*[1 << 30]byte)(unsafe.Pointer(ptr))[:len:len], nil
+}
+
+// Size returns the size in bytes of a memory region
+func Size(id uint32) uint {
+	if !state.CoreRunning {
+		return 0
+	}
+	return state.Core.GetMemorySize(id)
+}
+
+// inRange tells if length bytes starting at offset fit in a region of size
+// bytes, without overflowing
+func inRange(size, offset, length uint) bool {
+	return offset <= size && length <= size-offset
+}
+
+// Read copies length bytes of the memory region id, starting at offset
+func Read(id uint32, offset, length uint) ([]byte, error) {
+	mem, err := region(id)
+	if err != nil {
+		return nil, err
+	}
+	return read(mem, offset, length)
+}
+
+func read(mem []byte, offset, length uint) ([]byte, error) {
+	if !inRange(uint(len(mem)), offset, length) {
+		return nil, errors.New("address out of range")
+	}
+
+	out := make([]byte, length)
+	copy(out, mem[offset:offset+length])
+	return out, nil
+}
+
+// Write copies data to the memory region id, starting at offset
+func Write(id uint32, offset uint, data []byte) error {
+	mem, err := region(id)
+	if err != nil {
+		return err
+	}
+	return write(mem, offset, data)
+}
+
+func write(mem []byte, offset uint, data []byte) error {
+	if !inRange(uint(len(mem)), offset, uint(len(data))) {
+		return errors.New("address out of range")
+	}
+
+	copy(mem[offset:], data)
+	return nil
+}
+
+// reduce removes the bits set in mask from addr, compacting the remaining bits
+func reduce(addr, mask uintptr) uintptr {
+	for mask != 0 {
+		tmp := (mask - 1) &^ mask
+		addr = (addr & tmp) | ((addr >> 1) &^ tmp)
+		mask = (mask & (mask - 1)) >> 1
+	}
+	return addr
+}
+
+// mapped returns the memory starting at a given address of the emulated
+// system, as described by the memory map of the core. If the core doesn't
+// provide a memory map, the address is an offset in the system RAM.
+func mapped(addr uintptr) ([]byte, error) {
+	if !state.CoreRunning {
+		return nil, errors.New("core not running")
+	}
+
+	if len(state.Core.MemoryMap) == 0 {
+		mem, err := region(libretro.MemorySystemRAM)
+		if err != nil {
+			return nil, err
+		}
+		if addr >= uintptr(len(mem)) {
+			return nil, errors.New("address out of range")
+		}
+		return mem[addr:], nil
+	}
+
+	for _, d := range state.Core.MemoryMap {
+		if d.Ptr == nil || d.Len == 0 {
+			continue
+		}
+		if d.Select != 0 {
+			if addr&d.Select != d.Start&d.Select {
+				continue
+			}
+		} else if addr < d.Start || addr >= d.Start+d.Len {
+			continue
+		}
+
+		offset := reduce(addr-d.Start, d.Disconnect) % d.Len
+		mem := (*[1 << 30]byte)(d.Ptr)[d.Offset : d.Offset+d.Len : d.Offset+d.Len]
+		return mem[offset:], nil
+	}
+
+	return nil, errors.New("address not mapped")
+}
+
+// ReadAddress copies length bytes of the emulated system memory, starting at
+// addr
+func ReadAddress(addr uintptr, length uint) ([]byte, error) {
+	mem, err := mapped(addr)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint(len(mem)) {
+		length = uint(len(mem))
+	}
+
+	out := make([]byte, length)
+	copy(out, mem)
+	return out, nil
+}
+
+// WriteAddress copies data to the emulated system memory, starting at addr.
+// It returns the number of bytes written.
+func WriteAddress(addr uintptr, data []byte) (int, error) {
+	mem, err := mapped(addr)
+	if err != nil {
+		return 0, err
+	}
+	return copy(mem, data), nil
+}
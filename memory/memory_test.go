@@ -0,0 +1,57 @@
+package memory
+
+import "testing"
+
+func Test_reduce(t *testing.T) {
+	tests := []struct {
+		name       string
+		addr, mask uintptr
+		want       uintptr
+	}{
+		{"Empty mask leaves the address untouched", 0x1234, 0, 0x1234},
+		{"Removes a single bit", 0xb, 0x4, 0x7},
+		{"Removes the upper bits", 0xabcd, 0xf000, 0xbcd},
+		{"Removes several bits", 0xff, 0x22, 0x3f},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reduce(tt.addr, tt.mask); got != tt.want {
+				t.Errorf("reduce() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_read_write(t *testing.T) {
+	mem := []byte{1, 2, 3, 4}
+	huge := ^uint(0)
+	tests := []struct {
+		name           string
+		offset, length uint
+		ok             bool
+	}{
+		{"Whole region", 0, 4, true},
+		{"End of the region", 3, 1, true},
+		{"Empty read at the end", 4, 0, true},
+		{"Past the end", 3, 2, false},
+		{"Offset past the end", 5, 0, false},
+		{"Length wrapping around", 2, huge, false},
+		{"Offset wrapping around", huge, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := read(mem, tt.offset, tt.length)
+			if (err == nil) != tt.ok {
+				t.Fatalf("read() = %v, %v", got, err)
+			}
+			if tt.ok && uint(len(got)) != tt.length {
+				t.Errorf("read() = %v", got)
+			}
+			if tt.length <= 4 {
+				if err := write(mem, tt.offset, make([]byte, tt.length)); (err == nil) != tt.ok {
+					t.Errorf("write() = %v", err)
+				}
+			}
+		})
+	}
+}
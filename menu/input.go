@@ -1,10 +1,13 @@
 package menu
 
 import (
+	"sync/atomic"
+
 	"github.com/libretro/ludo/audio"
 	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/libretro"
 	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/savestates"
 	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 )
@@ -20,6 +23,11 @@ var (
 // Update takes care of calling the update method of the current scene.
 // Each scene has it's own input logic to allow a variety of navigation systems.
 func (m *Menu) Update(dt float32) {
+	if atomic.CompareAndSwapInt32(&tabsOutdated, 1, 0) {
+		refreshTabs()
+	}
+	updateScanTab()
+
 	currentScene := m.stack[len(m.stack)-1]
 	currentScene.update(dt)
 }
@@ -168,6 +176,8 @@ func (m *Menu) ProcessHotkeys() {
 		return
 	}
 
+	askSyncConflicts()
+
 	// First menu combo
 	if input.NewState[0][libretro.DeviceIDJoypadL3] == 1 && input.NewState[0][libretro.DeviceIDJoypadR3] == 1 {
 		combo1++
@@ -213,6 +223,21 @@ func (m *Menu) ProcessHotkeys() {
 		}
 	}
 
+	if state.CoreRunning && !state.MenuActive {
+		switch {
+		case input.Pressed[0][input.ActionSaveState] == 1:
+			saveSlot()
+		case input.Pressed[0][input.ActionLoadState] == 1:
+			loadSlot()
+		case input.Pressed[0][input.ActionNextSlot] == 1:
+			savestates.NextSlot()
+			showSlot()
+		case input.Pressed[0][input.ActionPrevSlot] == 1:
+			savestates.PrevSlot()
+			showSlot()
+		}
+	}
+
 	// Close if ActionShouldClose is pressed, but display a confirmation dialog
 	// in case a game is running
 	if input.Pressed[0][input.ActionShouldClose] == 1 {
@@ -109,6 +109,12 @@ func (m *Menu) ContextReset() {
 	}
 }
 
+// OfferResume resumes a game launched from the command line interface from
+// where it was left, depending on the AutoResume setting.
+func (m *Menu) OfferResume(gamePath string) {
+	offerResume(gamePath)
+}
+
 // WarpToQuickMenu loads the contextual menu for games that are launched from
 // the command line interface or from 'Load Game'.
 func (m *Menu) WarpToQuickMenu() {
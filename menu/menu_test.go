@@ -2,9 +2,11 @@ package menu
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/libretro/ludo/playlists"
 	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/video"
 
@@ -233,3 +235,86 @@ func TestExtractTags(t *testing.T) {
 		})
 	}
 }
+
+func Test_fuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		name  string
+		want  bool
+	}{
+		{"", "Super Mario Bros. (World)", true},
+		{"smb", "Super Mario Bros. (World)", true},
+		{"mario bros", "Super Mario Bros. (World)", true},
+		{"MARIO", "Super Mario Bros. (World)", true},
+		{"bms", "Super Mario Bros. (World)", false},
+		{"zelda", "Super Mario Bros. (World)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if _, got := fuzzyMatch(tt.query, tt.name); got != tt.want {
+				t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.query, tt.name, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Ranks word starts first", func(t *testing.T) {
+		best, _ := fuzzyMatch("smb", "Super Mario Bros. (World)")
+		worst, _ := fuzzyMatch("smb", "Sims Bomber (Japan)")
+		if best <= worst {
+			t.Errorf("got %d <= %d", best, worst)
+		}
+	})
+}
+
+func Test_searchGames(t *testing.T) {
+	csv := filepath.Join(os.TempDir(), "Nintendo - Nintendo Entertainment System.csv")
+	playlists.Playlists[csv] = playlists.Playlist{
+		{Path: "/roms/a.nes", Name: "Mega Man (USA)", Genre: "Action", Year: "1987"},
+		{Path: "/roms/b.nes", Name: "Mega Man 2 (Europe)", Genre: "Action", Year: "1989"},
+		{Path: "/roms/c.nes", Name: "Metroid (USA) (Beta)", Genre: "Adventure"},
+		{Path: "/roms/d.nes", Name: "Super Mario Bros. (World) (Hack)"},
+	}
+	defer playlists.Remove(csv)
+
+	names := func(results []searchResult) []string {
+		l := []string{}
+		for _, r := range results {
+			l = append(l, r.game.Name)
+		}
+		return l
+	}
+
+	tests := []struct {
+		name   string
+		filter searchFilter
+		want   []string
+	}{
+		{"No filter", searchFilter{}, []string{
+			"Mega Man (USA)", "Mega Man 2 (Europe)", "Metroid (USA) (Beta)", "Super Mario Bros. (World) (Hack)",
+		}},
+		{"Query", searchFilter{query: "mm2"}, []string{"Mega Man 2 (Europe)"}},
+		{"Tags", searchFilter{tags: []string{"USA", "Beta"}}, []string{"Metroid (USA) (Beta)"}},
+		{"Genre", searchFilter{genre: "Action"}, []string{"Mega Man (USA)", "Mega Man 2 (Europe)"}},
+		{"Year", searchFilter{genre: "Action", year: "1989"}, []string{"Mega Man 2 (Europe)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(searchGames([]string{csv}, tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchGames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Facets", func(t *testing.T) {
+		tags, genres, years := searchFacets([]string{csv})
+		if want := []string{"Beta", "Europe", "Hack", "USA", "World"}; !reflect.DeepEqual(tags, want) {
+			t.Errorf("tags = %v, want %v", tags, want)
+		}
+		if want := []string{"Action", "Adventure"}; !reflect.DeepEqual(genres, want) {
+			t.Errorf("genres = %v, want %v", genres, want)
+		}
+		if want := []string{"1987", "1989"}; !reflect.DeepEqual(years, want) {
+			t.Errorf("years = %v, want %v", years, want)
+		}
+	})
+}
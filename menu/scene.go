@@ -252,6 +252,16 @@ func askDeletePlaylistConfirmation(cb func()) {
 		}))
 }
 
+// Displays a confirmation dialog before deleting a collection
+func askDeleteCollectionConfirmation(cb func()) {
+	menu.Push(buildYesNoDialog(
+		"Confirm before deleting",
+		"You are about to delete a collection.",
+		"Games and game data won't be removed.", func() {
+			cb()
+		}))
+}
+
 // Displays a confirmation dialog before deleting a savestate
 func askDeleteSavestateConfirmation(cb func()) {
 	menu.Push(buildYesNoDialog(
@@ -0,0 +1,98 @@
+package menu
+
+import (
+	"os/user"
+
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/scanner"
+	"github.com/libretro/ludo/watcher"
+)
+
+type sceneAddGames struct {
+	entry
+}
+
+func buildAddGames() Scene {
+	var list sceneAddGames
+	list.label = "Add games"
+
+	usr, _ := user.Current()
+
+	list.children = append(list.children, entry{
+		label: "Scan a directory",
+		icon:  "scan",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildExplorer(usr.HomeDir, nil,
+				func(path string) {
+					if err := watcher.Add(path); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+					}
+					scanner.ScanDir(path, scheduleRefresh)
+				},
+				&entry{
+					label: "<Scan this directory>",
+					icon:  "scan",
+				},
+				nil,
+			))
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Import a RetroArch playlist",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildExplorer(usr.HomeDir, []string{".lpl"},
+				importPlaylist,
+				nil,
+				nil,
+			))
+		},
+	})
+
+	list.segueMount()
+
+	return &list
+}
+
+// importPlaylist is triggered when a .lpl file is selected in the explorer
+func importPlaylist(path string) {
+	added, err := playlists.Import(path, playlists.Rewrite{})
+	if err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", "Could not import playlist: %s", err.Error())
+		return
+	}
+	ntf.DisplayAndLog(ntf.Success, "Menu", "Done importing. %d new games found.", added)
+	refreshTabs()
+}
+
+func (s *sceneAddGames) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneAddGames) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneAddGames) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneAddGames) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneAddGames) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneAddGames) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneAddGames) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -0,0 +1,196 @@
+package menu
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/libretro/ludo/audit"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/scanner"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneAudit struct {
+	entry
+}
+
+// auditCategories are the lists displayed for each audited system
+var auditCategories = []struct {
+	label  string
+	status audit.Status
+}{
+	{"Have", audit.Have},
+	{"Missing", audit.Missing},
+	{"Bad Dumps", audit.BadDump},
+	{"Duplicates", audit.Duplicate},
+	{"Unrecognized", audit.Unrecognized},
+}
+
+func buildAudit() Scene {
+	var list sceneAudit
+	list.label = "Collection Audit"
+
+	report := audit.Audit(&state.DB, scanner.LoadIndex())
+	picks := audit.OneGameOneROM(&state.DB, report, settings.Current.RegionPriority)
+
+	for _, s := range report.Systems {
+		s := s
+		label := playlists.ShortName(s.Name)
+		value := fmt.Sprintf("%d/%d", s.Count(audit.Have), s.Total)
+		if s.Name == "" {
+			label = "Unrecognized Files"
+			value = fmt.Sprintf("%d", len(s.Rows))
+		}
+		list.children = append(list.children, entry{
+			label:       label,
+			icon:        "subsetting",
+			stringValue: func() string { return value },
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildAuditSystem(label, s, picks))
+			},
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "Scan your collection first",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildAuditSystem lists the categories of the audit of a system
+func buildAuditSystem(label string, s audit.System, picks []audit.Pick) Scene {
+	var list sceneAudit
+	list.label = label
+
+	if s.Name != "" {
+		var systemPicks []audit.Pick
+		for _, p := range picks {
+			if p.System == s.Name {
+				systemPicks = append(systemPicks, p)
+			}
+		}
+		list.children = append(list.children, entry{
+			label:       "One Game, One ROM",
+			icon:        "subsetting",
+			stringValue: func() string { return fmt.Sprintf("%d", len(systemPicks)) },
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildAuditPicks(systemPicks))
+			},
+		})
+	}
+
+	for _, c := range auditCategories {
+		c := c
+		var rows []audit.Row
+		for _, r := range s.Rows {
+			if r.Status == c.status {
+				rows = append(rows, r)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		list.children = append(list.children, entry{
+			label:       c.label,
+			icon:        "subsetting",
+			stringValue: func() string { return fmt.Sprintf("%d", len(rows)) },
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildAuditRows(c.label, rows))
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildAuditRows lists the games or files of a category
+func buildAuditRows(label string, rows []audit.Row) Scene {
+	var list sceneAudit
+	list.label = label
+
+	for _, r := range rows {
+		name := r.Game
+		if r.Status == audit.Unrecognized {
+			name = filepath.Base(r.Path)
+		}
+		list.children = append(list.children, entry{
+			label: name,
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildAuditPicks lists the version picked for each title of a system
+func buildAuditPicks(picks []audit.Pick) Scene {
+	var list sceneAudit
+	list.label = "One Game, One ROM"
+
+	for _, p := range picks {
+		p := p
+		list.children = append(list.children, entry{
+			label: p.Game,
+			icon:  "subsetting",
+			stringValue: func() string {
+				if p.Path == "" {
+					return "Missing"
+				}
+				return "Have"
+			},
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "No games in the database",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneAudit) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneAudit) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneAudit) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneAudit) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneAudit) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneAudit) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneAudit) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -0,0 +1,239 @@
+package menu
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/libretro/ludo/collections"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/settings"
+)
+
+// sceneCollection lists the games of a collection. Games of a collection
+// belong to different systems, like in the history, so it is rendered the same
+// way.
+type sceneCollection struct {
+	sceneHistory
+	name string
+}
+
+func buildCollection(name string) Scene {
+	var list sceneCollection
+	list.label = name
+	list.name = name
+
+	if err := collections.Relocate(name); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", "Could not update collection: %s", err.Error())
+	}
+
+	for _, game := range collections.Collections[name] {
+		game := game // needed for callbackOK
+		strippedName, tags := extractTags(game.Name)
+		list.children = append(list.children, entry{
+			label:      strippedName,
+			subLabel:   playlists.ShortName(game.System),
+			gameName:   game.Name,
+			path:       game.Path,
+			system:     game.System,
+			tags:       tags,
+			callbackOK: func() { loadCollectionEntry(&list, game) },
+			callbackX:  func() { askDeleteGameConfirmation(func() { deleteCollectionEntry(&list, game) }) },
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "Empty collection",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+	return &list
+}
+
+// playlistPath returns the path of the playlist of a system
+func playlistPath(system string) string {
+	return filepath.Join(settings.Current.PlaylistsDirectory, system+".csv")
+}
+
+// loadCollectionEntry runs a game of a collection with the core of its
+// playlist
+func loadCollectionEntry(list Scene, game collections.Game) {
+	loadPlaylistEntry(list, playlistPath(game.System), playlists.Game{
+		Path:  game.Path,
+		Name:  game.Name,
+		CRC32: game.CRC32,
+	})
+}
+
+func deleteCollectionEntry(list *sceneCollection, game collections.Game) {
+	if err := collections.Remove(list.name, game); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", "Could not update collection: %s", err.Error())
+		return
+	}
+	refreshTabs()
+
+	l := []entry{}
+	for _, e := range list.children {
+		if e.path != game.Path {
+			l = append(l, e)
+		}
+	}
+	list.children = l
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "Empty collection",
+			icon:  "subsetting",
+		})
+	}
+
+	if list.ptr >= len(list.children) {
+		list.ptr = len(list.children) - 1
+	}
+
+	genericAnimate(&list.entry)
+}
+
+// collectionGame builds the collection entry of a game of a playlist. The
+// CRC32 is taken from the playlist when the game is there.
+func collectionGame(system, path, name string) collections.Game {
+	g, _ := playlists.Find(playlistPath(system), path)
+	return collections.Game{
+		Path:   path,
+		Name:   name,
+		System: system,
+		CRC32:  g.CRC32,
+	}
+}
+
+// getCollections returns the tabs of the collections, the favorites first
+func getCollections() []entry {
+	var cols []entry
+	for _, name := range collections.Names() {
+		name := name
+		e := entry{
+			label:    name,
+			subLabel: fmt.Sprintf("%d Games", len(collections.Collections[name])),
+			icon:     "collection",
+			callbackOK: func() {
+				menu.Push(buildCollection(name))
+			},
+			callbackX: func() { askDeleteCollectionConfirmation(func() { deleteCollection(name) }) },
+		}
+		if name == collections.Favorites {
+			e.icon = "favorites"
+			e.callbackX = nil
+		}
+		cols = append(cols, e)
+	}
+	return cols
+}
+
+func deleteCollection(name string) {
+	if err := collections.Delete(name); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", "Could not delete collection: %s", err.Error())
+		return
+	}
+	menu.stack[0].Entry().ptr++
+	refreshTabs()
+}
+
+type sceneCollectionActions struct {
+	entry
+}
+
+// buildCollectionActions lists the collections, to add a game to them or
+// remove it from them
+func buildCollectionActions(game collections.Game) Scene {
+	var list sceneCollectionActions
+	list.label = "Collections"
+
+	var build func()
+	build = func() {
+		list.children = []entry{}
+		for _, name := range collections.Names() {
+			name := name
+			list.children = append(list.children, entry{
+				label: name,
+				icon:  "subsetting",
+				stringValue: func() string {
+					if collections.Contains(name, game) {
+						return "Added"
+					}
+					return ""
+				},
+				callbackOK: func() { toggleCollection(name, game) },
+			})
+		}
+		list.children = append(list.children, entry{
+			label: "New Collection",
+			icon:  "add",
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildKeyboard("Collection Name", func(name string) {
+					if err := collections.Create(name); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Menu", "Could not create collection: %s", err.Error())
+						return
+					}
+					toggleCollection(name, game)
+					build()
+				}))
+			},
+		})
+	}
+	build()
+
+	list.segueMount()
+
+	return &list
+}
+
+// toggleCollection adds a game to a collection, or removes it if it is already
+// there
+func toggleCollection(name string, game collections.Game) {
+	if collections.Contains(name, game) {
+		if err := collections.Remove(name, game); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Menu", "Could not update collection: %s", err.Error())
+			return
+		}
+		ntf.DisplayAndLog(ntf.Success, "Menu", "Removed from %s.", name)
+	} else {
+		if _, err := collections.Add(name, game); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Menu", "Could not update collection: %s", err.Error())
+			return
+		}
+		ntf.DisplayAndLog(ntf.Success, "Menu", "Added to %s.", name)
+	}
+	refreshTabs()
+}
+
+func (s *sceneCollectionActions) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneCollectionActions) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneCollectionActions) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneCollectionActions) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneCollectionActions) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneCollectionActions) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneCollectionActions) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -9,15 +9,28 @@ import (
 type sceneDialog struct {
 	entry
 	title, line1, line2 string
+	yes, no, alt        string
+	callbackNo          func()
+	callbackAlt         func()
 }
 
 func buildYesNoDialog(title, line1, line2 string, callbackOK func()) Scene {
+	return buildChoiceDialog(title, line1, line2, "YES", "NO", callbackOK, nil)
+}
+
+// buildChoiceDialog is a dialog offering two choices with custom labels, on
+// the OK and X buttons. Cancelling the dialog doesn't choose any of them.
+func buildChoiceDialog(title, line1, line2, yes, alt string, callbackYes, callbackAlt func()) Scene {
 	var list sceneDialog
 	list.label = "Confirm Dialog"
-	list.callbackOK = callbackOK
+	list.callbackOK = callbackYes
+	list.callbackAlt = callbackAlt
 	list.title = title
 	list.line1 = line1
 	list.line2 = line2
+	list.yes = yes
+	list.no = "CANCEL"
+	list.alt = alt
 	audio.PlayEffect(audio.Effects["notice"])
 	return &list
 }
@@ -44,11 +57,22 @@ func (s *sceneDialog) update(dt float32) {
 		s.callbackOK()
 	}
 
+	// Second choice
+	if input.Released[0][libretro.DeviceIDJoypadX] == 1 && s.callbackAlt != nil {
+		audio.PlayEffect(audio.Effects["ok"])
+		menu.stack[len(menu.stack)-2].segueBack()
+		menu.stack = menu.stack[:len(menu.stack)-1]
+		s.callbackAlt()
+	}
+
 	// Cancel
 	if input.Released[0][libretro.DeviceIDJoypadB] == 1 {
 		audio.PlayEffect(audio.Effects["cancel"])
 		menu.stack[len(menu.stack)-2].segueBack()
 		menu.stack = menu.stack[:len(menu.stack)-1]
+		if s.callbackNo != nil {
+			s.callbackNo()
+		}
 	}
 }
 
@@ -83,7 +107,7 @@ func (s *sceneDialog) render() {
 
 	var margin float32 = 15
 
-	_, _, _, a, b, _, _, _, _, _ := hintIcons()
+	_, _, _, a, b, x, _, _, _, _ := hintIcons()
 
 	menu.DrawImage(
 		b,
@@ -94,7 +118,20 @@ func (s *sceneDialog) render() {
 		fw/2-width/2*menu.ratio+margin*menu.ratio+70*menu.ratio,
 		fh/2+height/2*menu.ratio-23*menu.ratio-margin*menu.ratio,
 		0.4*menu.ratio,
-		"NO")
+		s.no)
+
+	if s.alt != "" {
+		menu.DrawImage(
+			x,
+			fw/2-75*menu.ratio,
+			fh/2+height/2*menu.ratio-70*menu.ratio-margin*menu.ratio,
+			70*menu.ratio, 70*menu.ratio, 1.0, darkGrey)
+		menu.Font.Printf(
+			fw/2-75*menu.ratio+70*menu.ratio,
+			fh/2+height/2*menu.ratio-23*menu.ratio-margin*menu.ratio,
+			0.4*menu.ratio,
+			s.alt)
+	}
 
 	menu.DrawImage(
 		a,
@@ -105,7 +142,7 @@ func (s *sceneDialog) render() {
 		fw/2+width/2*menu.ratio-150*menu.ratio-margin*menu.ratio+70*menu.ratio,
 		fh/2+height/2*menu.ratio-23*menu.ratio-margin*menu.ratio,
 		0.4*menu.ratio,
-		"YES")
+		s.yes)
 }
 
 func (s *sceneDialog) drawHintBar() {
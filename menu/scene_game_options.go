@@ -0,0 +1,132 @@
+package menu
+
+import (
+	"github.com/libretro/ludo/audio"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+type sceneGameOptions struct {
+	entry
+}
+
+// buildGameOptions shows the metadata of a game of a playlist and allows to
+// override the core used to run it
+func buildGameOptions(CSVPath, path string) Scene {
+	var list sceneGameOptions
+
+	game, _ := playlists.Find(CSVPath, path)
+	list.label = game.Name
+
+	// Read the game again, its core can change while the scene is displayed
+	current := func() playlists.Game {
+		g, _ := playlists.Find(CSVPath, path)
+		return g
+	}
+
+	list.children = append(list.children, entry{
+		label: "Set Core",
+		icon:  "subsetting",
+		stringValue: func() string {
+			if corePath := current().CorePath; corePath != "" {
+				return utils.FileName(corePath)
+			}
+			return "Playlist default"
+		},
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildExplorer(
+				settings.Current.CoresDirectory,
+				[]string{".dll", ".dylib", ".so"},
+				func(corePath string) {
+					playlists.SetCore(CSVPath, path, corePath)
+					ntf.DisplayAndLog(ntf.Success, "Menu", "Core set to %s.", utils.FileName(corePath))
+				},
+				nil,
+				prettifyCoreName,
+			))
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Use Playlist Core",
+		icon:  "subsetting",
+		callbackOK: func() {
+			audio.PlayEffect(audio.Effects["ok"])
+			playlists.SetCore(CSVPath, path, "")
+			ntf.DisplayAndLog(ntf.Success, "Menu", "Core override removed.")
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Collections",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildCollectionActions(collectionGame(utils.FileName(CSVPath), path, game.Name)))
+		},
+	})
+
+	info := []struct {
+		label string
+		value func(g playlists.Game) string
+	}{
+		{"Region", func(g playlists.Game) string { return g.Region }},
+		{"Genre", func(g playlists.Game) string { return g.Genre }},
+		{"Year", func(g playlists.Game) string { return g.Year }},
+		{"Developer", func(g playlists.Game) string { return g.Developer }},
+		{"Last Played", func(g playlists.Game) string {
+			if g.LastPlayed.IsZero() {
+				return "Never"
+			}
+			return g.LastPlayed.Format("2006-01-02 15:04")
+		}},
+	}
+	for _, i := range info {
+		i := i
+		list.children = append(list.children, entry{
+			label: i.label,
+			icon:  "subsetting",
+			stringValue: func() string {
+				if v := i.value(current()); v != "" {
+					return v
+				}
+				return "Unknown"
+			},
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func (s *sceneGameOptions) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneGameOptions) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneGameOptions) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneGameOptions) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneGameOptions) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneGameOptions) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneGameOptions) drawHintBar() {
+	genericDrawHintBar()
+}
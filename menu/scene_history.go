@@ -4,8 +4,11 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/libretro/ludo/audio"
 	"github.com/libretro/ludo/core"
 	"github.com/libretro/ludo/history"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/state"
 )
@@ -78,6 +81,7 @@ func loadHistoryEntry(list Scene, game history.Game) {
 		menu.Push(buildQuickMenu())
 		menu.tweens.FastForward() // position the elements without animating
 		state.MenuActive = false
+		offerResume(game.Path)
 	} else {
 		list.segueNext()
 		menu.Push(buildQuickMenu())
@@ -145,6 +149,15 @@ func (s *sceneHistory) segueBack() {
 
 func (s *sceneHistory) update(dt float32) {
 	genericInput(&s.entry, dt)
+
+	// Y
+	if input.Released[0][libretro.DeviceIDJoypadY] == 1 {
+		if e := s.children[s.ptr]; e.path != "" {
+			audio.PlayEffect(audio.Effects["ok"])
+			s.segueNext()
+			menu.Push(buildCollectionActions(collectionGame(e.system, e.path, e.gameName)))
+		}
+	}
 }
 
 // Override rendering
@@ -235,7 +248,7 @@ func (s *sceneHistory) drawHintBar() {
 	w, h := menu.GetFramebufferSize()
 	menu.DrawRect(0, float32(h)-70*menu.ratio, float32(w), 70*menu.ratio, 0, lightGrey)
 
-	_, upDown, _, a, b, x, _, _, _, guide := hintIcons()
+	_, upDown, _, a, b, x, y, _, _, guide := hintIcons()
 
 	var stack float32
 	if state.CoreRunning {
@@ -249,4 +262,7 @@ func (s *sceneHistory) drawHintBar() {
 	if list.children[list.ptr].callbackX != nil {
 		stackHint(&stack, x, "DELETE", h)
 	}
+	if list.children[list.ptr].path != "" {
+		stackHint(&stack, y, "COLLECTIONS", h)
+	}
 }
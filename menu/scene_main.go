@@ -8,6 +8,7 @@ import (
 	"github.com/libretro/ludo/core"
 	"github.com/libretro/ludo/history"
 	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/playlists"
 	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/utils"
@@ -111,6 +112,24 @@ func buildMainMenu() Scene {
 		},
 	})
 
+	list.children = append(list.children, entry{
+		label: "Search Games",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildSearch("Search Games", playlists.Paths()))
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label: "Collection Audit",
+		icon:  "subsetting",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildAudit())
+		},
+	})
+
 	if state.LudOS {
 		list.children = append(list.children, entry{
 			label: "Updater",
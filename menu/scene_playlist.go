@@ -6,8 +6,11 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/libretro/ludo/audio"
 	"github.com/libretro/ludo/core"
 	"github.com/libretro/ludo/history"
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/playlists"
 	"github.com/libretro/ludo/settings"
@@ -17,13 +20,16 @@ import (
 
 type scenePlaylist struct {
 	entry
+	csvPath string // path of the playlist file
 }
 
 func buildPlaylist(path string) Scene {
 	var list scenePlaylist
 	list.label = utils.FileName(path)
+	list.csvPath = path
 
-	for _, game := range playlists.Playlists[path] {
+	playlist := playlists.Get(path)
+	for _, game := range playlist {
 		game := game // needed for callbackOK
 		strippedName, tags := extractTags(game.Name)
 		list.children = append(list.children, entry{
@@ -32,12 +38,12 @@ func buildPlaylist(path string) Scene {
 			path:       game.Path,
 			tags:       tags,
 			icon:       utils.FileName(path) + "-content",
-			callbackOK: func() { loadPlaylistEntry(&list, list.label, game) },
+			callbackOK: func() { loadPlaylistEntry(&list, path, game) },
 			callbackX:  func() { askDeleteGameConfirmation(func() { deletePlaylistEntry(&list, path, game) }) },
 		})
 	}
 
-	if len(playlists.Playlists[path]) == 0 {
+	if len(playlist) == 0 {
 		list.children = append(list.children, entry{
 			label: "Empty playlist",
 			icon:  "subsetting",
@@ -83,15 +89,24 @@ func extractTags(name string) (string, []string) {
 	return name, tags
 }
 
-func loadPlaylistEntry(list *scenePlaylist, playlist string, game playlists.Game) {
+func loadPlaylistEntry(list Scene, csvPath string, game playlists.Game) {
+	playlist := utils.FileName(csvPath)
+	// The core of the game might have been changed since the list was built
+	if g, ok := playlists.Find(csvPath, game.Path); ok {
+		game = g
+	}
 	if _, err := os.Stat(game.Path); os.IsNotExist(err) {
 		ntf.DisplayAndLog(ntf.Error, "Menu", "Game not found.")
 		return
 	}
-	corePath, err := settings.CoreForPlaylist(playlist)
-	if err != nil {
-		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
-		return
+	corePath := game.CorePath
+	if corePath == "" {
+		var err error
+		corePath, err = settings.CoreForPlaylist(playlist)
+		if err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+			return
+		}
 	}
 	if _, err := os.Stat(corePath); os.IsNotExist(err) {
 		ntf.DisplayAndLog(ntf.Error, "Menu", "Core not found: %s", filepath.Base(corePath))
@@ -114,10 +129,12 @@ func loadPlaylistEntry(list *scenePlaylist, playlist string, game playlists.Game
 			System:   playlist,
 			CorePath: corePath,
 		})
+		playlists.Touch(csvPath, game.Path)
 		list.segueNext()
 		menu.Push(buildQuickMenu())
 		menu.tweens.FastForward() // position the elements without animating
 		state.MenuActive = false
+		offerResume(game.Path)
 	} else {
 		list.segueNext()
 		menu.Push(buildQuickMenu())
@@ -146,12 +163,11 @@ func removePlaylistEntry(s []entry, game playlists.Game) []entry {
 }
 
 func deletePlaylistEntry(list *scenePlaylist, path string, game playlists.Game) {
-	playlists.Playlists[path] = removePlaylistGame(playlists.Playlists[path], game)
-	playlists.Save(path)
+	playlists.Set(path, removePlaylistGame(playlists.Get(path), game))
 	refreshTabs()
 	list.children = removePlaylistEntry(list.children, game)
 
-	if len(playlists.Playlists[path]) == 0 {
+	if playlists.Count(path) == 0 {
 		list.children = append(list.children, entry{
 			label: "Empty playlist",
 			icon:  "subsetting",
@@ -185,6 +201,22 @@ func (s *scenePlaylist) segueBack() {
 
 func (s *scenePlaylist) update(dt float32) {
 	genericInput(&s.entry, dt)
+
+	// Y
+	if input.Released[0][libretro.DeviceIDJoypadY] == 1 {
+		if e := s.children[s.ptr]; e.path != "" {
+			audio.PlayEffect(audio.Effects["ok"])
+			s.segueNext()
+			menu.Push(buildGameOptions(s.csvPath, e.path))
+		}
+	}
+
+	// Select, unless it was held for a hotkey or the Start+Select menu combo
+	if input.Released[0][libretro.DeviceIDJoypadSelect] == 1 && !input.SelectCombo[0] {
+		audio.PlayEffect(audio.Effects["ok"])
+		s.segueNext()
+		menu.Push(buildSearch(s.label, []string{s.csvPath}))
+	}
 }
 
 // Override rendering
@@ -263,7 +295,7 @@ func (s *scenePlaylist) drawHintBar() {
 	w, h := menu.GetFramebufferSize()
 	menu.DrawRect(0, float32(h)-70*menu.ratio, float32(w), 70*menu.ratio, 0, lightGrey)
 
-	_, upDown, _, a, b, x, _, _, _, guide := hintIcons()
+	_, upDown, _, a, b, x, y, _, slct, guide := hintIcons()
 
 	var stack float32
 	if state.CoreRunning {
@@ -277,4 +309,8 @@ func (s *scenePlaylist) drawHintBar() {
 	if list.children[list.ptr].callbackX != nil {
 		stackHint(&stack, x, "DELETE", h)
 	}
+	if list.children[list.ptr].path != "" {
+		stackHint(&stack, y, "OPTIONS", h)
+	}
+	stackHint(&stack, slct, "SEARCH", h)
 }
@@ -42,6 +42,25 @@ func buildQuickMenu() Scene {
 		},
 	})
 
+	list.children = append(list.children, entry{
+		label: "Restore SRAM Backup",
+		icon:  "states",
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildSRAMBackups())
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label:       "Softpatch",
+		icon:        "subsetting",
+		stringValue: appliedPatches,
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildSoftpatch())
+		},
+	})
+
 	list.children = append(list.children, entry{
 		label: "Take Screenshot",
 		icon:  "screenshot",
@@ -1,11 +1,13 @@
 package menu
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/libretro/ludo/history"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/savestates"
 	"github.com/libretro/ludo/settings"
@@ -46,11 +48,12 @@ func buildSavestates() Scene {
 	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
 	for _, path := range paths {
 		path := path
-		date := strings.Replace(utils.FileName(path), gameName+"@", "", 1)
+		suffix := strings.Replace(utils.FileName(path), gameName+"@", "", 1)
 		list.children = append(list.children, entry{
-			label: "Load " + date,
-			icon:  "loadstate",
-			path:  path,
+			label:    savestateLabel(suffix),
+			subLabel: savestateSubLabel(path),
+			icon:     "loadstate",
+			path:     path,
 			callbackOK: func() {
 				err := savestates.Load(path)
 				if err != nil {
@@ -70,6 +73,82 @@ func buildSavestates() Scene {
 	return &list
 }
 
+// saveSlot saves the state of the game and a thumbnail in the current slot
+func saveSlot() {
+	name := savestates.SlotName(state.GamePath, savestates.Slot)
+	if err := menu.TakeScreenshot(name); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+	}
+	if err := savestates.SaveSlot(); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+		return
+	}
+	ntf.DisplayAndLog(ntf.Success, "Menu", "State saved to %s.", savestates.SlotLabel(savestates.Slot))
+}
+
+// loadSlot loads the state of the game from the current slot
+func loadSlot() {
+	if err := savestates.LoadSlot(); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+		return
+	}
+	ntf.DisplayAndLog(ntf.Success, "Menu", "State loaded from %s.", savestates.SlotLabel(savestates.Slot))
+}
+
+// showSlot displays the current slot, and whether it is empty
+func showSlot() {
+	msg := "State slot: " + savestates.SlotLabel(savestates.Slot)
+	if _, err := os.Stat(savestates.SlotPath(state.GamePath, savestates.Slot)); os.IsNotExist(err) {
+		msg += " (empty)"
+	}
+	ntf.DisplayAndLog(ntf.Info, "Menu", msg)
+}
+
+// savestateLabel returns the label of a savestate entry, from the part of its
+// name following the game name
+func savestateLabel(suffix string) string {
+	if suffix == "auto" {
+		return "Load " + savestates.SlotLabel(savestates.AutoSlot)
+	}
+	var slot int
+	if _, err := fmt.Sscanf(suffix, "slot%d", &slot); err == nil {
+		return "Load " + savestates.SlotLabel(slot)
+	}
+	return "Load " + suffix
+}
+
+// offerResume loads the savestate created when the game was last closed. The
+// AutoResume setting decides if it is loaded directly or after a confirmation.
+func offerResume(gamePath string) {
+	game, ok := history.Find(gamePath)
+	if !ok || game.Savestate == "" {
+		return
+	}
+	if _, err := os.Stat(game.Savestate); os.IsNotExist(err) {
+		return
+	}
+
+	resume := func() {
+		if err := savestates.Load(game.Savestate); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+			return
+		}
+		state.MenuActive = false
+		ntf.DisplayAndLog(ntf.Success, "Menu", "Game resumed.")
+	}
+
+	switch settings.Current.AutoResume {
+	case "Always":
+		resume()
+	case "Ask":
+		state.MenuActive = true
+		menu.Push(buildYesNoDialog(
+			"Resume",
+			"Do you want to resume from where you left off?",
+			"Press B to start from the beginning.", resume))
+	}
+}
+
 func (s *sceneSavestates) Entry() *entry {
 	return &s.entry
 }
@@ -101,8 +180,18 @@ func removeSavestateEntry(s []entry, path string) []entry {
 	return l
 }
 
+// savestateSubLabel describes the core and the size of a savestate, from its
+// metadata
+func savestateSubLabel(path string) string {
+	m, err := savestates.ReadMetadata(path)
+	if err != nil {
+		return "No metadata"
+	}
+	return fmt.Sprintf("%s %s, %d KB, CRC %08X", m.CoreName, m.CoreVersion, m.Size/1024, m.ContentCRC)
+}
+
 func deleteSavestateEntry(list *sceneSavestates, path string) {
-	err := os.Remove(path)
+	err := savestates.Delete(path)
 	if err != nil {
 		ntf.DisplayAndLog(ntf.Error, "Menu", "Could not delete savestate: %s", err.Error())
 		return
@@ -151,11 +240,23 @@ func (s *sceneSavestates) render() {
 					e.scale, textColor.Alpha(e.iconAlpha))
 			}
 
+			// Offset on Y to vertically center label + sublabel if there is a sublabel
+			slOffset := float32(0)
+			if e.subLabel != "" {
+				slOffset = 30 * menu.ratio * e.subLabelAlpha
+			}
+
 			menu.Font.SetColor(textColor.Alpha(e.labelAlpha))
 			menu.Font.Printf(
 				840*menu.ratio,
-				float32(h)*e.yp+fontOffset,
+				float32(h)*e.yp+fontOffset-slOffset,
 				0.5*menu.ratio, e.label)
+
+			menu.Font.SetColor(mediumGrey.Alpha(e.subLabelAlpha))
+			menu.Font.Printf(
+				840*menu.ratio,
+				float32(h)*e.yp+fontOffset+60*menu.ratio-slOffset,
+				0.5*menu.ratio, e.subLabel)
 		}
 	}
 }
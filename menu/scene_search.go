@@ -0,0 +1,224 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/utils"
+)
+
+type sceneSearch struct {
+	entry
+}
+
+// buildSearch lets the user filter the games of one or several playlists by
+// name, tags, genre and year
+func buildSearch(label string, paths []string) Scene {
+	var list sceneSearch
+	list.label = label
+
+	var filter searchFilter
+	results := searchGames(paths, filter)
+	refresh := func() { results = searchGames(paths, filter) }
+	tags, genres, years := searchFacets(paths)
+
+	list.children = append(list.children, entry{
+		label: "Name",
+		icon:  "subsetting",
+		stringValue: func() string {
+			if filter.query == "" {
+				return "Any"
+			}
+			return filter.query
+		},
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildKeyboard("Search", func(query string) {
+				filter.query = query
+				refresh()
+			}))
+		},
+	})
+
+	if len(tags) > 0 {
+		list.children = append(list.children, entry{
+			label: "Tags",
+			icon:  "subsetting",
+			stringValue: func() string {
+				if len(filter.tags) == 0 {
+					return "Any"
+				}
+				return strings.Join(filter.tags, ", ")
+			},
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildSearchChoices("Tags", tags,
+					func(tag string) bool { return containsString(filter.tags, tag) },
+					func(tag string) {
+						if containsString(filter.tags, tag) {
+							var l []string
+							for _, t := range filter.tags {
+								if t != tag {
+									l = append(l, t)
+								}
+							}
+							filter.tags = l
+						} else {
+							filter.tags = append(filter.tags, tag)
+						}
+						refresh()
+					},
+				))
+			},
+		})
+	}
+
+	// Genre and year are only known for games found in the database
+	facets := []struct {
+		label  string
+		values []string
+		value  *string
+	}{
+		{"Genre", genres, &filter.genre},
+		{"Year", years, &filter.year},
+	}
+	for _, f := range facets {
+		f := f
+		if len(f.values) == 0 {
+			continue
+		}
+		list.children = append(list.children, entry{
+			label: f.label,
+			icon:  "subsetting",
+			stringValue: func() string {
+				if *f.value == "" {
+					return "Any"
+				}
+				return *f.value
+			},
+			callbackOK: func() {
+				list.segueNext()
+				menu.Push(buildSearchChoices(f.label, f.values,
+					func(v string) bool { return *f.value == v },
+					func(v string) {
+						if *f.value == v {
+							*f.value = ""
+						} else {
+							*f.value = v
+						}
+						refresh()
+					},
+				))
+			},
+		})
+	}
+
+	list.children = append(list.children, entry{
+		label: "Clear Filters",
+		icon:  "subsetting",
+		callbackOK: func() {
+			filter = searchFilter{}
+			refresh()
+		},
+	})
+
+	list.children = append(list.children, entry{
+		label:       "Show Results",
+		icon:        "subsetting",
+		stringValue: func() string { return fmt.Sprintf("%d Games", len(results)) },
+		callbackOK: func() {
+			list.segueNext()
+			menu.Push(buildSearchResults(results))
+		},
+	})
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildSearchChoices lists the possible values of a filter, OK toggles them
+func buildSearchChoices(label string, values []string, selected func(string) bool, toggle func(string)) Scene {
+	var list sceneSearch
+	list.label = label
+
+	for _, v := range values {
+		v := v
+		list.children = append(list.children, entry{
+			label: v,
+			icon:  "subsetting",
+			stringValue: func() string {
+				if selected(v) {
+					return "ON"
+				}
+				return ""
+			},
+			callbackOK: func() { toggle(v) },
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// buildSearchResults lists the games found. They can belong to different
+// systems, so they are displayed like the history.
+func buildSearchResults(results []searchResult) Scene {
+	var list sceneHistory
+	list.label = "Results"
+
+	for _, r := range results {
+		r := r // needed for callbackOK
+		system := utils.FileName(r.csvPath)
+		strippedName, tags := extractTags(r.game.Name)
+		list.children = append(list.children, entry{
+			label:      strippedName,
+			subLabel:   playlists.ShortName(system),
+			gameName:   r.game.Name,
+			path:       r.game.Path,
+			system:     system,
+			tags:       tags,
+			callbackOK: func() { loadPlaylistEntry(&list, r.csvPath, r.game) },
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "No games found",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+	return &list
+}
+
+func (s *sceneSearch) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneSearch) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneSearch) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneSearch) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneSearch) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneSearch) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneSearch) drawHintBar() {
+	genericDrawHintBar()
+}
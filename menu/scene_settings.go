@@ -4,16 +4,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fatih/structs"
 	"github.com/go-gl/glfw/v3.3/glfw"
 
 	"github.com/libretro/ludo/audio"
+	"github.com/libretro/ludo/control"
+	"github.com/libretro/ludo/input"
 	"github.com/libretro/ludo/ludos"
 	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/utils"
+	"github.com/libretro/ludo/watcher"
 )
 
 type sceneSettings struct {
@@ -77,6 +81,29 @@ func buildSettings() Scene {
 					))
 				},
 			})
+		} else if w := f.Tag("widget"); w == "text" || w == "password" {
+			// Text settings, typed with the virtual keyboard
+			list.children = append(list.children, entry{
+				label: f.Tag("label"),
+				icon:  "subsetting",
+				value: f.Value,
+				stringValue: func() string {
+					v := f.Value().(string)
+					if w == "password" {
+						v = strings.Repeat("*", len(v))
+					}
+					return fmt.Sprintf(f.Tag("fmt"), v)
+				},
+				callbackOK: func() {
+					list.segueNext()
+					menu.Push(buildKeyboard(f.Tag("label"), func(v string) {
+						f.Set(v)
+						if err := settings.Save(); err != nil {
+							ntf.DisplayAndLog(ntf.Error, "Settings", err.Error())
+						}
+					}))
+				},
+			})
 		} else {
 			// Regular settings
 			list.children = append(list.children, entry{
@@ -209,6 +236,19 @@ var incrCallbacks = map[string]callbackIncrement{
 		f.Set(v)
 		settings.Save()
 	},
+	"HotkeyButton": func(f *structs.Field, direction int) {
+		v := f.Value().(string)
+		i := utils.IndexOfString(v, input.HotkeyButtons)
+		i += direction
+		if i < 0 {
+			i = len(input.HotkeyButtons) - 1
+		}
+		if i > len(input.HotkeyButtons)-1 {
+			i = 0
+		}
+		f.Set(input.HotkeyButtons[i])
+		settings.Save()
+	},
 	"AudioVolume": func(f *structs.Field, direction int) {
 		v := f.Value().(float32)
 		v += 0.1 * float32(direction)
@@ -241,6 +281,104 @@ var incrCallbacks = map[string]callbackIncrement{
 		f.Set(v)
 		settings.Save()
 	},
+	"SavestatesCompression": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		f.Set(v)
+		settings.Save()
+	},
+	"SoftpatchFullpath": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		f.Set(v)
+		settings.Save()
+	},
+	"AutoResume": func(f *structs.Field, direction int) {
+		modes := []string{"Always", "Ask", "Never"}
+		v := f.Value().(string)
+		i := utils.IndexOfString(v, modes)
+		i += direction
+		if i < 0 {
+			i = len(modes) - 1
+		}
+		if i > len(modes)-1 {
+			i = 0
+		}
+		f.Set(modes[i])
+		settings.Save()
+	},
+	"SRAMBackups": func(f *structs.Field, direction int) {
+		v := f.Value().(int)
+		v += direction
+		if v < 0 {
+			v = 0
+		}
+		if v > 20 {
+			v = 20
+		}
+		f.Set(v)
+		settings.Save()
+	},
+	"SyncBackend": func(f *structs.Field, direction int) {
+		backends := []string{"Off", "WebDAV", "S3"}
+		v := f.Value().(string)
+		i := utils.IndexOfString(v, backends)
+		i += direction
+		if i < 0 {
+			i = len(backends) - 1
+		}
+		if i > len(backends)-1 {
+			i = 0
+		}
+		f.Set(backends[i])
+		settings.Save()
+	},
+	"ControlServer": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		var err error
+		if v {
+			err = control.Start()
+		} else {
+			err = control.Stop()
+		}
+		if err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Settings", err.Error())
+			return
+		}
+		f.Set(v)
+		settings.Save()
+	},
+	"NetworkCommands": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		var err error
+		if v {
+			err = control.StartNetworkCommands()
+		} else {
+			err = control.StopNetworkCommands()
+		}
+		if err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Settings", err.Error())
+			return
+		}
+		f.Set(v)
+		settings.Save()
+	},
+	"WatchDirectories": func(f *structs.Field, direction int) {
+		v := f.Value().(bool)
+		v = !v
+		if v {
+			if err := watcher.Start(); err != nil {
+				ntf.DisplayAndLog(ntf.Error, "Settings", err.Error())
+				return
+			}
+		} else {
+			watcher.Stop()
+		}
+		f.Set(v)
+		settings.Save()
+	},
 	"SSHService":       ludos.ServiceSettingIncrCallback,
 	"SambaService":     ludos.ServiceSettingIncrCallback,
 	"BluetoothService": ludos.ServiceSettingIncrCallback,
@@ -0,0 +1,109 @@
+package menu
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/libretro/ludo/core"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/patch"
+	"github.com/libretro/ludo/state"
+)
+
+type sceneSoftpatch struct {
+	entry
+}
+
+func buildSoftpatch() Scene {
+	var list sceneSoftpatch
+	list.label = "Softpatch"
+
+	gamePath := state.GamePath
+	choose := func(label, choice string) entry {
+		return entry{
+			label: label,
+			icon:  "subsetting",
+			stringValue: func() string {
+				if choice == patch.Choice(gamePath) {
+					return "Active"
+				}
+				return ""
+			},
+			callbackOK: func() {
+				if choice == patch.Choice(gamePath) {
+					return
+				}
+				askSoftpatchConfirmation(func() {
+					if err := patch.SetChoice(gamePath, choice); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+						return
+					}
+					core.UnloadGame()
+					if err := core.LoadGame(gamePath); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+						return
+					}
+					ntf.DisplayAndLog(ntf.Success, "Menu", "Patch: %s", appliedPatches())
+					state.MenuActive = false
+				})
+			},
+		}
+	}
+
+	list.children = append(list.children, choose("Auto", ""))
+	list.children = append(list.children, choose("None", patch.None))
+	for _, path := range patch.Available(gamePath) {
+		list.children = append(list.children, choose(filepath.Base(path), path))
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+// appliedPatches returns the names of the patches applied to the running game
+func appliedPatches() string {
+	if len(state.Patches) == 0 {
+		return "None"
+	}
+	names := []string{}
+	for _, path := range state.Patches {
+		names = append(names, filepath.Base(path))
+	}
+	return strings.Join(names, ", ")
+}
+
+func askSoftpatchConfirmation(cb func()) {
+	menu.Push(buildYesNoDialog(
+		"Confirm before patching",
+		"The game will be restarted with this patch.",
+		"Unsaved progress will be lost.", cb))
+}
+
+func (s *sceneSoftpatch) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneSoftpatch) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneSoftpatch) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneSoftpatch) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneSoftpatch) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneSoftpatch) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneSoftpatch) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -0,0 +1,89 @@
+package menu
+
+import (
+	"strings"
+
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/savefiles"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+type sceneSRAMBackups struct {
+	entry
+}
+
+func buildSRAMBackups() Scene {
+	var list sceneSRAMBackups
+	list.label = "Restore SRAM Backup"
+
+	gameName := utils.FileName(state.GamePath)
+	paths, err := savefiles.Backups()
+	if err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+	}
+	for _, path := range paths {
+		path := path
+		date := strings.Replace(utils.FileName(path), gameName+"@", "", 1)
+		list.children = append(list.children, entry{
+			label: "Restore " + date,
+			icon:  "loadstate",
+			path:  path,
+			callbackOK: func() {
+				askRestoreSRAMConfirmation(func() {
+					if err := savefiles.RestoreBackup(path); err != nil {
+						ntf.DisplayAndLog(ntf.Error, "Menu", err.Error())
+						return
+					}
+					ntf.DisplayAndLog(ntf.Success, "Menu", "SRAM restored, reset the game to use it.")
+				})
+			},
+		})
+	}
+
+	if len(list.children) == 0 {
+		list.children = append(list.children, entry{
+			label: "No backup",
+			icon:  "subsetting",
+		})
+	}
+
+	list.segueMount()
+
+	return &list
+}
+
+func askRestoreSRAMConfirmation(cb func()) {
+	menu.Push(buildYesNoDialog(
+		"Confirm before restoring",
+		"The current SRAM will be replaced by this backup.",
+		"It will be backed up first.", cb))
+}
+
+func (s *sceneSRAMBackups) Entry() *entry {
+	return &s.entry
+}
+
+func (s *sceneSRAMBackups) segueMount() {
+	genericSegueMount(&s.entry)
+}
+
+func (s *sceneSRAMBackups) segueNext() {
+	genericSegueNext(&s.entry)
+}
+
+func (s *sceneSRAMBackups) segueBack() {
+	genericAnimate(&s.entry)
+}
+
+func (s *sceneSRAMBackups) update(dt float32) {
+	genericInput(&s.entry, dt)
+}
+
+func (s *sceneSRAMBackups) render() {
+	genericRender(&s.entry)
+}
+
+func (s *sceneSRAMBackups) drawHintBar() {
+	genericDrawHintBar()
+}
@@ -3,8 +3,7 @@ package menu
 import (
 	"fmt"
 	"os"
-	"os/user"
-	"sort"
+	"sync/atomic"
 
 	"github.com/libretro/ludo/audio"
 	"github.com/libretro/ludo/input"
@@ -63,17 +62,11 @@ func buildTabs() Scene {
 		subLabel: "Scan your collection",
 		icon:     "add",
 		callbackOK: func() {
-			usr, _ := user.Current()
-			menu.Push(buildExplorer(usr.HomeDir, nil,
-				func(path string) {
-					scanner.ScanDir(path, refreshTabs)
-				},
-				&entry{
-					label: "<Scan this directory>",
-					icon:  "scan",
-				},
-				nil,
-			))
+			if len(scanner.Running()) > 0 {
+				askCancelScanConfirmation()
+				return
+			}
+			menu.Push(buildAddGames())
 		},
 	})
 
@@ -82,6 +75,21 @@ func buildTabs() Scene {
 	return &list
 }
 
+// tabsOutdated is set when the playlists changed in another goroutine
+var tabsOutdated int32
+
+// scheduleRefresh asks for the tabs to be rebuilt on the main thread, it can
+// be called from any goroutine
+func scheduleRefresh() {
+	atomic.StoreInt32(&tabsOutdated, 1)
+}
+
+// RefreshTabs asks for the tabs to be rebuilt, for example after a scan. It
+// can be called from any goroutine, the tabs are rebuilt on the next update.
+func (m *Menu) RefreshTabs() {
+	scheduleRefresh()
+}
+
 // refreshTabs is called after playlist scanning is complete. It inserts the new
 // playlists in the tabs, and makes sure that all the icons are positioned and
 // sized properly.
@@ -125,21 +133,12 @@ func refreshTabs() {
 	}
 }
 
-// getPlaylists browse the filesystem for CSV files, parse them and returns
-// a list of menu entries. It is used in the tabs, but could be used somewhere
-// else too.
+// getPlaylists returns a list of menu entries for the collections and the
+// playlists in memory. It is used in the tabs, but could be used somewhere else
+// too.
 func getPlaylists() []entry {
-	playlists.Load()
-
-	// To store the keys in slice in sorted order
-	var keys []string
-	for k := range playlists.Playlists {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	var pls []entry
-	for _, path := range keys {
+	pls := getCollections()
+	for _, path := range playlists.Paths() {
 		path := path
 		filename := utils.FileName(path)
 		count := playlists.Count(path)
@@ -157,6 +156,32 @@ func getPlaylists() []entry {
 	return pls
 }
 
+// updateScanTab displays the progress of the running scans under the last tab
+func updateScanTab() {
+	e := menu.stack[0].Entry()
+	tab := &e.children[len(e.children)-1]
+	jobs := scanner.Running()
+	if len(jobs) == 0 {
+		tab.subLabel = "Scan your collection"
+		return
+	}
+	tab.subLabel = "Scanning " + jobs[0].Progress().String()
+}
+
+// askCancelScanConfirmation proposes to stop the running scans
+func askCancelScanConfirmation() {
+	menu.Push(buildYesNoDialog(
+		"Confirm before canceling",
+		"A scan is in progress.",
+		"Do you want to cancel it?",
+		func() {
+			for _, j := range scanner.Running() {
+				j.Cancel()
+			}
+		},
+	))
+}
+
 func deletePlaylist(path string) {
 	err := os.Remove(path)
 	if err != nil {
@@ -164,7 +189,7 @@ func deletePlaylist(path string) {
 		return
 	}
 	menu.stack[0].Entry().ptr++
-	delete(playlists.Playlists, path)
+	playlists.Remove(path)
 	refreshTabs()
 }
 
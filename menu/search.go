@@ -0,0 +1,126 @@
+package menu
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/libretro/ludo/playlists"
+)
+
+// searchFilter narrows down the games of one or several playlists
+type searchFilter struct {
+	query string   // Fuzzy matched against the game names
+	tags  []string // Tags from extractTags, the games must have all of them
+	genre string
+	year  string
+}
+
+// searchResult is a game matching a searchFilter
+type searchResult struct {
+	csvPath string // Playlist of the game
+	game    playlists.Game
+	score   int
+}
+
+// fuzzyMatch checks if the letters of query appear in s in the same order,
+// ignoring case and spaces. The score is higher when the letters are
+// consecutive or start words, so "smb" ranks "Super Mario Bros." first.
+func fuzzyMatch(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	r := []rune(strings.ToLower(s))
+	score, qi, prev := 0, 0, -2
+	for i := 0; i < len(r) && qi < len(q); i++ {
+		if r[i] != q[qi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(r[i-1]) && !unicode.IsDigit(r[i-1]) {
+			score += 3
+		}
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// match checks if a game passes the filter, and returns its score
+func (f searchFilter) match(game playlists.Game) (int, bool) {
+	if f.genre != "" && game.Genre != f.genre {
+		return 0, false
+	}
+	if f.year != "" && game.Year != f.year {
+		return 0, false
+	}
+	if len(f.tags) > 0 {
+		_, tags := extractTags(game.Name)
+		for _, want := range f.tags {
+			if !containsString(tags, want) {
+				return 0, false
+			}
+		}
+	}
+	return fuzzyMatch(f.query, game.Name)
+}
+
+// searchGames returns the games of the playlists passing the filter, the best
+// matches first
+func searchGames(paths []string, f searchFilter) []searchResult {
+	var results []searchResult
+	for _, path := range paths {
+		for _, game := range playlists.Get(path) {
+			if score, ok := f.match(game); ok {
+				results = append(results, searchResult{path, game, score})
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].game.Name < results[j].game.Name
+	})
+	return results
+}
+
+// searchFacets returns the tags, genres and years found in the playlists, to
+// be proposed as filters
+func searchFacets(paths []string) (tags, genres, years []string) {
+	seen := map[string]bool{}
+	add := func(list *[]string, kind, value string) {
+		if value == "" || seen[kind+value] {
+			return
+		}
+		seen[kind+value] = true
+		*list = append(*list, value)
+	}
+	for _, path := range paths {
+		for _, game := range playlists.Get(path) {
+			_, gameTags := extractTags(game.Name)
+			for _, tag := range gameTags {
+				add(&tags, "tag", tag)
+			}
+			add(&genres, "genre", game.Genre)
+			add(&years, "year", game.Year)
+		}
+	}
+	sort.Strings(tags)
+	sort.Strings(genres)
+	sort.Strings(years)
+	return
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package menu
+
+import (
+	"path/filepath"
+	"strings"
+
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/savefiles"
+	"github.com/libretro/ludo/savesync"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// askSyncConflicts asks the user which version to keep for each file that
+// changed on this device and on the sync server. A cancelled conflict is left
+// unresolved, the next sync reports it again.
+func askSyncConflicts() {
+	for _, c := range savesync.Conflicts() {
+		c := c
+		state.MenuActive = true
+		menu.Push(buildChoiceDialog(
+			"Save sync conflict",
+			filepath.Base(c.Name)+" changed here and on the server.",
+			"Here: "+c.Local.ModTime.Local().Format("2006-01-02 15:04")+
+				", server: "+c.Remote.ModTime.Local().Format("2006-01-02 15:04"),
+			"LOCAL", "SERVER",
+			func() { resolveSyncConflict(c, true) },
+			func() { resolveSyncConflict(c, false) },
+		))
+	}
+}
+
+func resolveSyncConflict(c savesync.Conflict, keepLocal bool) {
+	if err := savesync.Resolve(c, keepLocal); err != nil {
+		ntf.DisplayAndLog(ntf.Error, "Sync", err.Error())
+		return
+	}
+	// Reload the SRAM or RTC if it was replaced by the version of the server
+	if !keepLocal && isGameSavefile(c.Name) {
+		if err := savefiles.LoadSRAM(); err != nil {
+			ntf.DisplayAndLog(ntf.Error, "Sync", err.Error())
+			return
+		}
+	}
+	ntf.DisplayAndLog(ntf.Success, "Sync", "Conflict resolved: %s", filepath.Base(c.Name))
+}
+
+// isGameSavefile tells if a synchronized file is the SRAM or RTC of the running
+// game
+func isGameSavefile(name string) bool {
+	return state.CoreRunning && filepath.Dir(name) == "savefiles" &&
+		strings.HasPrefix(filepath.Base(name), utils.FileName(state.GamePath)+".")
+}
@@ -0,0 +1,163 @@
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+)
+
+// BPS actions
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// bpsMaxNumber bounds the variable length numbers, sizes and offsets never
+// need more and it fits in an int on 32-bit platforms
+const bpsMaxNumber = math.MaxInt32
+
+// bpsDecode reads a variable length number at the given offset of the patch
+func bpsDecode(patch []byte, offset *int) (int, error) {
+	var data uint64
+	var shift uint64 = 1
+	for {
+		if *offset >= len(patch) {
+			return 0, errors.New("invalid patch")
+		}
+		x := patch[*offset]
+		*offset++
+		data += uint64(x&0x7f) * shift
+		if data > bpsMaxNumber {
+			return 0, errors.New("invalid patch")
+		}
+		if x&0x80 != 0 {
+			return int(data), nil
+		}
+		shift <<= 7
+		data += shift
+	}
+}
+
+// bpsRelative reads a signed relative offset used by the copy actions
+func bpsRelative(patch []byte, offset *int) (int, error) {
+	data, err := bpsDecode(patch, offset)
+	if err != nil {
+		return 0, err
+	}
+	if data&1 != 0 {
+		return -(data >> 1), nil
+	}
+	return data >> 1, nil
+}
+
+func applyBPS(patch, source []byte) (*[]byte, error) {
+	if len(patch) < 19 {
+		return nil, errors.New("patch too small")
+	}
+
+	if string(patch[0:4]) != "BPS1" {
+		return nil, errors.New("invalid patch header")
+	}
+
+	footer := len(patch) - 12
+	sourceChecksum := binary.LittleEndian.Uint32(patch[footer:])
+	targetChecksum := binary.LittleEndian.Uint32(patch[footer+4:])
+	patchChecksum := binary.LittleEndian.Uint32(patch[footer+8:])
+
+	if crc32.ChecksumIEEE(patch[:footer+8]) != patchChecksum {
+		return nil, errors.New("invalid patch")
+	}
+
+	offset := 4
+	sourceSize, err := bpsDecode(patch, &offset)
+	if err != nil {
+		return nil, err
+	}
+	targetSize, err := bpsDecode(patch, &offset)
+	if err != nil {
+		return nil, err
+	}
+	metadataSize, err := bpsDecode(patch, &offset)
+	if err != nil {
+		return nil, err
+	}
+	if metadataSize > footer-offset {
+		return nil, errors.New("invalid patch")
+	}
+	offset += metadataSize
+	if targetSize > maxTargetSize {
+		return nil, errors.New("patch too big")
+	}
+
+	if len(source) != sourceSize || crc32.ChecksumIEEE(source) != sourceChecksum {
+		return nil, errors.New("invalid source")
+	}
+
+	target := make([]byte, targetSize)
+	outputOffset := 0
+	sourceRelativeOffset := 0
+	targetRelativeOffset := 0
+
+	for offset < footer {
+		data, err := bpsDecode(patch, &offset)
+		if err != nil {
+			return nil, err
+		}
+		length := (data >> 2) + 1
+		if outputOffset+length > targetSize {
+			return nil, errors.New("invalid patch")
+		}
+
+		switch data & 3 {
+		case bpsSourceRead:
+			if outputOffset+length > len(source) {
+				return nil, errors.New("invalid patch")
+			}
+			copy(target[outputOffset:], source[outputOffset:outputOffset+length])
+			outputOffset += length
+		case bpsTargetRead:
+			if offset+length > footer {
+				return nil, errors.New("invalid patch")
+			}
+			copy(target[outputOffset:], patch[offset:offset+length])
+			offset += length
+			outputOffset += length
+		case bpsSourceCopy:
+			rel, err := bpsRelative(patch, &offset)
+			if err != nil {
+				return nil, err
+			}
+			sourceRelativeOffset += rel
+			if sourceRelativeOffset < 0 || sourceRelativeOffset+length > len(source) {
+				return nil, errors.New("invalid patch")
+			}
+			copy(target[outputOffset:], source[sourceRelativeOffset:sourceRelativeOffset+length])
+			sourceRelativeOffset += length
+			outputOffset += length
+		case bpsTargetCopy:
+			rel, err := bpsRelative(patch, &offset)
+			if err != nil {
+				return nil, err
+			}
+			targetRelativeOffset += rel
+			if targetRelativeOffset < 0 || targetRelativeOffset >= outputOffset {
+				return nil, errors.New("invalid patch")
+			}
+			// The copy can overlap the bytes being written, copy byte per byte
+			for ; length > 0; length-- {
+				target[outputOffset] = target[targetRelativeOffset]
+				outputOffset++
+				targetRelativeOffset++
+			}
+		}
+	}
+
+	if crc32.ChecksumIEEE(target) != targetChecksum {
+		return nil, errors.New("invalid target")
+	}
+
+	return &target, nil
+}
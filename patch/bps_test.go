@@ -0,0 +1,130 @@
+package patch
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// bpsPatch wraps actions with the BPS header and footer
+func bpsPatch(source, target, actions []byte) []byte {
+	p := []byte("BPS1")
+	p = append(p, encodeNumber(uint64(len(source)))...)
+	p = append(p, encodeNumber(uint64(len(target)))...)
+	p = append(p, encodeNumber(0)...)
+	p = append(p, actions...)
+	return appendChecksums(p, source, target)
+}
+
+func Test_encodeNumber(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 300, 16511, 16512, 1 << 24} {
+		offset := 0
+		got, err := bpsDecode(encodeNumber(uint64(n)), &offset)
+		if err != nil || got != n {
+			t.Errorf("bpsDecode(encodeNumber(%d)) = %d, %v", n, got, err)
+		}
+	}
+}
+
+func Test_applyBPS(t *testing.T) {
+	source := []byte("abcdef")
+	target := []byte("abcXYabcXYab")
+	actions := []byte{}
+	actions = append(actions, encodeNumber((3-1)<<2|bpsSourceRead)...)
+	actions = append(actions, encodeNumber((2-1)<<2|bpsTargetRead)...)
+	actions = append(actions, 'X', 'Y')
+	actions = append(actions, encodeNumber((3-1)<<2|bpsSourceCopy)...)
+	actions = append(actions, encodeNumber(0)...)
+	actions = append(actions, encodeNumber((4-1)<<2|bpsTargetCopy)...)
+	actions = append(actions, encodeNumber(3<<1)...)
+
+	t.Run("Can apply a valid BPS patch", func(t *testing.T) {
+		got, err := applyBPS(bpsPatch(source, target, actions), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, target) {
+			t.Errorf("applyBPS() = %q, want %q", *got, target)
+		}
+	})
+
+	t.Run("Can detect a wrong source", func(t *testing.T) {
+		got, err := applyBPS(bpsPatch(source, target, actions), []byte("abcdeg"))
+		if err == nil || err.Error() != "invalid source" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid source")
+		}
+		if got != nil {
+			t.Errorf("applyBPS() = %v, want %v", got, nil)
+		}
+	})
+
+	t.Run("Can detect a corrupted patch", func(t *testing.T) {
+		p := bpsPatch(source, target, actions)
+		p[10] ^= 0xff
+		_, err := applyBPS(p, source)
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid patch")
+		}
+	})
+
+	t.Run("Can detect a wrong target", func(t *testing.T) {
+		_, err := applyBPS(bpsPatch(source, []byte("abcXYabcXYac"), actions), source)
+		if err == nil || err.Error() != "invalid target" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid target")
+		}
+	})
+
+	t.Run("Can detect a wrong header", func(t *testing.T) {
+		p := bpsPatch(source, target, actions)
+		p[0] = 'U'
+		_, err := applyBPS(p, source)
+		if err == nil || err.Error() != "invalid patch header" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid patch header")
+		}
+	})
+}
+
+func Test_applyBPS_corrupt(t *testing.T) {
+	source := []byte("abcdef")
+
+	t.Run("Rejects a huge target size", func(t *testing.T) {
+		p := []byte("BPS1")
+		p = append(p, encodeNumber(uint64(len(source)))...)
+		p = append(p, encodeNumber(maxTargetSize+1)...)
+		p = append(p, encodeNumber(0)...)
+		_, err := applyBPS(appendChecksums(p, source, nil), source)
+		if err == nil || err.Error() != "patch too big" {
+			t.Errorf("applyBPS() = %v, want %v", err, "patch too big")
+		}
+	})
+
+	t.Run("Rejects an overflowing number", func(t *testing.T) {
+		p := []byte("BPS1")
+		p = append(p, bytes.Repeat([]byte{0x7f}, 12)...)
+		p = append(p, 0x80)
+		_, err := applyBPS(appendChecksums(p, source, nil), source)
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid patch")
+		}
+	})
+
+	t.Run("Rejects a number too big for an int", func(t *testing.T) {
+		p := []byte("BPS1")
+		p = append(p, encodeNumber(1<<40)...)
+		_, err := applyBPS(appendChecksums(p, source, nil), source)
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid patch")
+		}
+	})
+
+	t.Run("Rejects metadata past the end", func(t *testing.T) {
+		p := []byte("BPS1")
+		p = append(p, encodeNumber(uint64(len(source)))...)
+		p = append(p, encodeNumber(uint64(len(source)))...)
+		p = append(p, encodeNumber(1000)...)
+		_, err := applyBPS(appendChecksums(p, source, source), source)
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyBPS() = %v, want %v", err, "invalid patch")
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package patch
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libretro/ludo/settings"
+)
+
+// cacheDir returns the folder of the patched ROMs directory identified by key
+func cacheDir(key uint32) string {
+	return filepath.Join(settings.Current.PatchedDirectory, fmt.Sprintf("%08x", key))
+}
+
+// writeFile writes a file of the cache, through a temporary file so a partial
+// file is never mistaken for a complete one
+func writeFile(path string, bytes []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, bytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Fullpath patches a game for cores that need a path. It returns the path of
+// the patched ROM in the cache and the patches applied, or an empty path if
+// the game has no patch. Disc images described by a cue sheet get their
+// tracks patched.
+//
+// The patched ROM keeps the name of the original ROM because cores often rely
+// on the extension. It is stored in a folder identified by the ROM path, size
+// and modification time and by the patches, so the ROM is only read and
+// patched when it is not in the cache yet. The other patched versions of the
+// same ROM are removed to keep the cache small.
+func Fullpath(gamePath, romPath string) (string, []string, error) {
+	if strings.ToLower(filepath.Ext(romPath)) == ".cue" {
+		return Cue(gamePath, romPath)
+	}
+
+	paths := Patches(gamePath, romPath)
+	if len(paths) == 0 {
+		return "", nil, nil
+	}
+	fi, err := os.Stat(romPath)
+	if err != nil {
+		return "", nil, err
+	}
+	key := crc32.NewIEEE()
+	fmt.Fprintf(key, "%s:%d:%d:", romPath, fi.Size(), fi.ModTime().UnixNano())
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(key, "%d:", len(data))
+		key.Write(data)
+	}
+
+	name := filepath.Base(romPath)
+	dir := cacheDir(key.Sum32())
+	path := filepath.Join(dir, name)
+	if exists(path) {
+		return path, paths, nil
+	}
+
+	bytes, err := ioutil.ReadFile(romPath)
+	if err != nil {
+		return "", nil, err
+	}
+	patched, err := Apply(paths, bytes)
+	if err != nil {
+		return "", nil, err
+	}
+	prune(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", nil, err
+	}
+	if err := writeFile(path, *patched); err != nil {
+		return "", nil, err
+	}
+	return path, paths, nil
+}
+
+// prune removes the cached versions of a ROM or a disc image
+func prune(name string) {
+	dirs, err := ioutil.ReadDir(settings.Current.PatchedDirectory)
+	if err != nil {
+		return
+	}
+	for _, fi := range dirs {
+		dir := filepath.Join(settings.Current.PatchedDirectory, fi.Name())
+		if fi.IsDir() && exists(filepath.Join(dir, name)) {
+			os.RemoveAll(dir)
+		}
+	}
+}
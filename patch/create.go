@@ -0,0 +1,222 @@
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Create produces a patch turning source into target. The format is one of
+// the supported extensions, like ".bps".
+func Create(format string, source, target []byte) ([]byte, error) {
+	switch format {
+	case ".bps":
+		return createBPS(source, target), nil
+	case ".ups":
+		return createUPS(source, target), nil
+	case ".ips":
+		return createIPS(source, target)
+	}
+	return nil, errors.New("unknown patch format " + format)
+}
+
+// encodeNumber writes a variable length number, as used by UPS and BPS
+func encodeNumber(n uint64) []byte {
+	var b []byte
+	for {
+		x := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(b, 0x80|x)
+		}
+		b = append(b, x)
+		n--
+	}
+}
+
+// appendChecksums appends the source and target CRC32, then the CRC32 of the
+// whole patch, in little endian
+func appendChecksums(patch, source, target []byte) []byte {
+	footer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(source))
+	patch = append(patch, footer...)
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(target))
+	patch = append(patch, footer...)
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(patch))
+	return append(patch, footer...)
+}
+
+// at returns the byte at i, or 0 past the end of data
+func at(data []byte, i int) byte {
+	if i < len(data) {
+		return data[i]
+	}
+	return 0
+}
+
+// IPS limits
+const (
+	ipsMaxSize   = 1 << 24
+	ipsMaxRecord = 0xffff
+)
+
+func createIPS(source, target []byte) ([]byte, error) {
+	if len(target) > ipsMaxSize {
+		return nil, errors.New("target too big for IPS")
+	}
+
+	patch := []byte("PATCH")
+	i := 0
+	for i < len(target) {
+		if i < len(source) && source[i] == target[i] {
+			i++
+			continue
+		}
+		// An offset reading EOF would end the patch, start one byte earlier
+		start := i
+		if start == EOF {
+			start--
+		}
+		end := i
+		for end < len(target) && end-start < ipsMaxRecord &&
+			(end >= len(source) || source[end] != target[end]) {
+			end++
+		}
+		patch = append(patch,
+			byte(start>>16), byte(start>>8), byte(start),
+			byte((end-start)>>8), byte(end-start))
+		patch = append(patch, target[start:end]...)
+		i = end
+	}
+	patch = append(patch, 'E', 'O', 'F')
+
+	if len(target) < len(source) {
+		patch = append(patch, byte(len(target)>>16), byte(len(target)>>8), byte(len(target)))
+	}
+	return patch, nil
+}
+
+func createUPS(source, target []byte) []byte {
+	patch := []byte("UPS1")
+	patch = append(patch, encodeNumber(uint64(len(source)))...)
+	patch = append(patch, encodeNumber(uint64(len(target)))...)
+
+	size := len(source)
+	if len(target) > size {
+		size = len(target)
+	}
+	last := 0
+	for i := 0; i < size; {
+		if at(source, i) == at(target, i) {
+			i++
+			continue
+		}
+		patch = append(patch, encodeNumber(uint64(i-last))...)
+		for ; i < size && at(source, i) != at(target, i); i++ {
+			patch = append(patch, at(source, i)^at(target, i))
+		}
+		// The terminator also stands for the next, unchanged, byte
+		patch = append(patch, 0)
+		i++
+		last = i
+	}
+
+	return appendChecksums(patch, source, target)
+}
+
+// bpsMinMatch is the shortest copy worth an action, shorter runs are written
+// as literals
+const bpsMinMatch = 4
+
+// bpsKey hashes the bytes at the start of a potential match
+func bpsKey(data []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(data[i : i+bpsMinMatch])
+}
+
+// bpsMatch returns the length of the common prefix of a[i:] and b[j:]
+func bpsMatch(a []byte, i int, b []byte, j, max int) int {
+	n := 0
+	for i+n < len(a) && j+n < max && a[i+n] == b[j+n] {
+		n++
+	}
+	return n
+}
+
+func bpsAction(action, length int) []byte {
+	return encodeNumber(uint64((length-1)<<2 | action))
+}
+
+func bpsOffset(delta int) []byte {
+	if delta < 0 {
+		return encodeNumber(uint64(-delta<<1 | 1))
+	}
+	return encodeNumber(uint64(delta << 1))
+}
+
+func createBPS(source, target []byte) []byte {
+	patch := []byte("BPS1")
+	patch = append(patch, encodeNumber(uint64(len(source)))...)
+	patch = append(patch, encodeNumber(uint64(len(target)))...)
+	patch = append(patch, encodeNumber(0)...) // no metadata
+
+	sourceIndex := map[uint32]int{}
+	for i := len(source) - bpsMinMatch; i >= 0; i-- {
+		sourceIndex[bpsKey(source, i)] = i
+	}
+	targetIndex := map[uint32]int{}
+
+	sourceRelativeOffset := 0
+	targetRelativeOffset := 0
+	literal := 0 // start of the pending TargetRead
+	indexed := 0 // target bytes added to targetIndex
+
+	flush := func(end int) {
+		if end > literal {
+			patch = append(patch, bpsAction(bpsTargetRead, end-literal)...)
+			patch = append(patch, target[literal:end]...)
+		}
+	}
+
+	for o := 0; o < len(target); {
+		for ; indexed+bpsMinMatch <= o; indexed++ {
+			targetIndex[bpsKey(target, indexed)] = indexed
+		}
+
+		action, length, from := bpsSourceRead, bpsMatch(target, o, source, o, len(source)), 0
+		if o+bpsMinMatch <= len(target) {
+			key := bpsKey(target, o)
+			if j, ok := sourceIndex[key]; ok {
+				if n := bpsMatch(target, o, source, j, len(source)); n > length {
+					action, length, from = bpsSourceCopy, n, j
+				}
+			}
+			if j, ok := targetIndex[key]; ok {
+				// The copy can overlap the bytes being written
+				if n := bpsMatch(target, o, target, j, len(target)); n > length {
+					action, length, from = bpsTargetCopy, n, j
+				}
+			}
+		}
+
+		if length < bpsMinMatch {
+			o++
+			continue
+		}
+
+		flush(o)
+		patch = append(patch, bpsAction(action, length)...)
+		switch action {
+		case bpsSourceCopy:
+			patch = append(patch, bpsOffset(from-sourceRelativeOffset)...)
+			sourceRelativeOffset = from + length
+		case bpsTargetCopy:
+			patch = append(patch, bpsOffset(from-targetRelativeOffset)...)
+			targetRelativeOffset = from + length
+		}
+		o += length
+		literal = o
+	}
+	flush(len(target))
+
+	return appendChecksums(patch, source, target)
+}
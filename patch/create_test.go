@@ -0,0 +1,122 @@
+package patch
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// roms returns pairs of original and modified ROMs covering the common edits
+func roms() map[string][2][]byte {
+	r := rand.New(rand.NewSource(1))
+	original := make([]byte, 70000)
+	r.Read(original)
+
+	modify := func(f func(b []byte) []byte) []byte {
+		b := append([]byte{}, original...)
+		return f(b)
+	}
+
+	return map[string][2][]byte{
+		"identical": {original, original},
+		"scattered changes": {original, modify(func(b []byte) []byte {
+			for i := 0; i < len(b); i += 997 {
+				b[i] ^= 0x55
+			}
+			return b
+		})},
+		"expanded": {original, modify(func(b []byte) []byte {
+			return append(b, bytes.Repeat([]byte("translation"), 1000)...)
+		})},
+		"truncated": {original, modify(func(b []byte) []byte {
+			return b[:50000]
+		})},
+		"moved blocks": {original, modify(func(b []byte) []byte {
+			return append(append(append([]byte{}, b[40000:]...), b[:20000]...), b[20000:40000]...)
+		})},
+		"changes at the EOF offset": {make([]byte, EOF+10), modify(func(b []byte) []byte {
+			t := make([]byte, EOF+10)
+			t[EOF] = 1
+			t[EOF+1] = 2
+			return t
+		})},
+		"empty original": {[]byte{}, []byte("new content")},
+	}
+}
+
+func Test_Create(t *testing.T) {
+	for _, format := range []string{".bps", ".ups", ".ips"} {
+		for name, pair := range roms() {
+			source, target := pair[0], pair[1]
+			t.Run(format+" "+name, func(t *testing.T) {
+				p, err := Create(format, source, target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := ApplyData(p, source)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(*got, target) {
+					t.Errorf("ApplyData(Create()) gives %d bytes, want %d bytes", len(*got), len(target))
+				}
+			})
+		}
+	}
+
+	t.Run("BPS uses copies for moved blocks", func(t *testing.T) {
+		pair := roms()["moved blocks"]
+		p, _ := Create(".bps", pair[0], pair[1])
+		if len(p) > 100 {
+			t.Errorf("Create() = %d bytes, want less than 100", len(p))
+		}
+	})
+
+	t.Run("Rejects unknown formats", func(t *testing.T) {
+		if _, err := Create(".xdelta", nil, nil); err == nil {
+			t.Errorf("Create() should fail")
+		}
+	})
+}
+
+func Test_Inspect(t *testing.T) {
+	source := []byte("original content")
+	target := []byte("modified content, longer")
+
+	for _, format := range []string{".bps", ".ups"} {
+		t.Run(format, func(t *testing.T) {
+			p, _ := Create(format, source, target)
+			info, err := Inspect(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Format != format || !info.Valid ||
+				info.SourceSize != len(source) || info.TargetSize != len(target) {
+				t.Errorf("Inspect() = %+v", info)
+			}
+
+			p[len(p)-1] ^= 0xff
+			info, _ = Inspect(p)
+			if info.Valid {
+				t.Errorf("Inspect() should detect a corrupted patch")
+			}
+		})
+	}
+
+	t.Run(".ips", func(t *testing.T) {
+		p, _ := Create(".ips", source, []byte("xriginal contenx"))
+		info, err := Inspect(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Format != ".ips" || info.Records != 2 {
+			t.Errorf("Inspect() = %+v", info)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		if _, err := Inspect([]byte("not a patch at all")); err == nil {
+			t.Errorf("Inspect() should fail")
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package patch
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cueFile matches the FILE commands of a cue sheet, the file name is either
+// quoted or a single word
+var cueFile = regexp.MustCompile(`(?mi)^(\s*FILE\s+)("[^"]*"|\S+)(.*)$`)
+
+// cueTrack is a file referenced by a cue sheet
+type cueTrack struct {
+	start, end int // location of the file name in the cue sheet
+	path       string
+	patches    []string
+}
+
+// cueTracks lists the files of a cue sheet with the patches to apply to them.
+// The patches of the game, or the one picked in the menu, go to the first
+// track. The other tracks only use the patches located next to them.
+func cueTracks(gamePath, cuePath string, cue []byte) []cueTrack {
+	var tracks []cueTrack
+	for i, m := range cueFile.FindAllSubmatchIndex(cue, -1) {
+		name := strings.Trim(string(cue[m[4]:m[5]]), `"`)
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(cuePath), name)
+		}
+		t := cueTrack{start: m[4], end: m[5], path: path}
+		if i == 0 {
+			t.patches = Patches(gamePath, path)
+		} else if Choice(gamePath) == "" {
+			t.patches = Find(path, path)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// Cue patches the tracks of a disc image described by a cue sheet. The patched
+// tracks are written to the patched ROMs directory, along with a cue sheet
+// pointing at them. The tracks without patch are referenced from their
+// original location. It returns the path of the new cue sheet and the patches
+// applied, or an empty path if no track has a patch.
+func Cue(gamePath, cuePath string) (string, []string, error) {
+	cue, err := ioutil.ReadFile(cuePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The cache is identified by the cue sheet and the patches, so the tracks
+	// don't have to be patched to know if they are in the cache already
+	key := crc32.NewIEEE()
+	key.Write(cue)
+	var applied []string
+	tracks := cueTracks(gamePath, cuePath, cue)
+	for _, t := range tracks {
+		for _, p := range t.patches {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return "", nil, err
+			}
+			fmt.Fprintf(key, "%s:%d:", t.path, len(data))
+			key.Write(data)
+			applied = append(applied, p)
+		}
+	}
+	if len(applied) == 0 {
+		return "", nil, nil
+	}
+
+	dir := cacheDir(key.Sum32())
+	name := filepath.Base(cuePath)
+	path := filepath.Join(dir, name)
+	if exists(path) {
+		return path, applied, nil
+	}
+
+	prune(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", nil, err
+	}
+
+	// Rewrite the cue sheet from the end, so the locations stay valid
+	rewritten := append([]byte{}, cue...)
+	for i := len(tracks) - 1; i >= 0; i-- {
+		t := tracks[i]
+		ref := t.path
+		if len(t.patches) > 0 {
+			ref = filepath.Base(t.path)
+			if err := patchTrack(t, filepath.Join(dir, ref)); err != nil {
+				os.RemoveAll(dir)
+				return "", nil, err
+			}
+		}
+		quoted := []byte(`"` + ref + `"`)
+		rewritten = append(rewritten[:t.start], append(quoted, rewritten[t.end:]...)...)
+	}
+
+	// The cue sheet is written last, its presence means the cache is complete
+	if err := writeFile(path, rewritten); err != nil {
+		return "", nil, err
+	}
+	return path, applied, nil
+}
+
+func patchTrack(t cueTrack, path string) error {
+	bytes, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+	patched, err := Apply(t.patches, bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(t.path), err)
+	}
+	return writeFile(path, *patched)
+}
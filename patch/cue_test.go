@@ -0,0 +1,82 @@
+package patch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/libretro/ludo/settings"
+)
+
+func Test_Cue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-cue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings.Current.PatchedDirectory = filepath.Join(dir, "patched")
+
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Game.cue", `FILE "Game (Track 1).bin" BINARY
+  TRACK 01 MODE2/2352
+    INDEX 01 00:00:00
+FILE "Game (Track 2).bin" BINARY
+  TRACK 02 AUDIO
+    INDEX 01 00:00:00
+`)
+	write("Game (Track 1).bin", "data track")
+	write("Game (Track 2).bin", "audio track")
+	cue := filepath.Join(dir, "Game.cue")
+
+	t.Run("Returns nothing without patch", func(t *testing.T) {
+		path, patches, err := Cue(cue, cue)
+		if path != "" || patches != nil || err != nil {
+			t.Errorf("Cue() = %v, %v, %v", path, patches, err)
+		}
+	})
+
+	write("Game.ips", string(ipsPatch(0, 'D')))
+
+	t.Run("Patches the first track", func(t *testing.T) {
+		path, patches, err := Cue(cue, cue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(patches) != 1 || patches[0] != filepath.Join(dir, "Game.ips") {
+			t.Errorf("Cue() = %v", patches)
+		}
+
+		sheet, _ := ioutil.ReadFile(path)
+		if !strings.Contains(string(sheet), `FILE "Game (Track 1).bin" BINARY`) ||
+			!strings.Contains(string(sheet), `FILE "`+filepath.Join(dir, "Game (Track 2).bin")+`" BINARY`) {
+			t.Errorf("Cue() wrote %s", sheet)
+		}
+
+		track, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(path), "Game (Track 1).bin"))
+		if string(track) != "Data track" {
+			t.Errorf("Cue() patched %q", track)
+		}
+	})
+
+	t.Run("Replaces the cached image when patches change", func(t *testing.T) {
+		first, _, _ := Cue(cue, cue)
+		write("Game (Track 2).ips", string(ipsPatch(0, 'A')))
+		second, patches, err := Cue(cue, cue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == second || exists(first) || len(patches) != 2 {
+			t.Errorf("Cue() = %v, %v", second, patches)
+		}
+		track, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(second), "Game (Track 2).bin"))
+		if string(track) != "Audio track" {
+			t.Errorf("Cue() patched %q", track)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Info describes a patch. IPS and PPF patches don't store checksums, only
+// their format and number of records are known. Nothing is known about
+// xdelta patches without decoding them.
+type Info struct {
+	Format     string
+	SourceSize int
+	TargetSize int
+	SourceCRC  uint32
+	TargetCRC  uint32
+	PatchCRC   uint32
+	Valid      bool // whether the patch checksum matches
+	Records    int
+}
+
+// Inspect reads the header and footer of a patch
+func Inspect(patch []byte) (*Info, error) {
+	switch {
+	case len(patch) >= 8 && string(patch[0:5]) == "PATCH":
+		return inspectIPS(patch)
+	case len(patch) >= 18 && string(patch[0:4]) == "UPS1":
+		return inspectChecksummed(".ups", patch)
+	case len(patch) >= 19 && string(patch[0:4]) == "BPS1":
+		return inspectChecksummed(".bps", patch)
+	case len(patch) >= 56 && string(patch[0:3]) == "PPF":
+		return inspectPPF(patch)
+	case len(patch) >= 5 && string(patch[0:4]) == "\xd6\xc3\xc4\x00":
+		return &Info{Format: ".xdelta", Valid: true}, nil
+	}
+	return nil, errors.New("unknown patch format")
+}
+
+func inspectChecksummed(format string, patch []byte) (*Info, error) {
+	info := Info{Format: format}
+	offset := 4
+	var err error
+	if info.SourceSize, err = bpsDecode(patch, &offset); err != nil {
+		return nil, err
+	}
+	if info.TargetSize, err = bpsDecode(patch, &offset); err != nil {
+		return nil, err
+	}
+	footer := len(patch) - 12
+	info.SourceCRC = binary.LittleEndian.Uint32(patch[footer:])
+	info.TargetCRC = binary.LittleEndian.Uint32(patch[footer+4:])
+	info.PatchCRC = binary.LittleEndian.Uint32(patch[footer+8:])
+	info.Valid = crc32.ChecksumIEEE(patch[:footer+8]) == info.PatchCRC
+	return &info, nil
+}
+
+func inspectIPS(patch []byte) (*Info, error) {
+	info := Info{Format: ".ips", Valid: true}
+	offset := 5
+	for offset+3 <= len(patch) {
+		address := int(patch[offset])<<16 | int(patch[offset+1])<<8 | int(patch[offset+2])
+		offset += 3
+		if address == EOF {
+			if offset+3 == len(patch) {
+				info.TargetSize = int(patch[offset])<<16 | int(patch[offset+1])<<8 | int(patch[offset+2])
+			}
+			return &info, nil
+		}
+		if offset+2 > len(patch) {
+			break
+		}
+		length := int(patch[offset])<<8 | int(patch[offset+1])
+		offset += 2
+		if length == 0 { // RLE
+			length = 3
+		}
+		offset += length
+		info.Records++
+	}
+	return nil, errors.New("invalid patch")
+}
+
+func inspectPPF(patch []byte) (*Info, error) {
+	info := Info{Format: ".ppf", Valid: true}
+	version := patch[3]
+	offset := 56
+	undo := false
+	switch version {
+	case '2':
+		if len(patch) < 1084 {
+			return nil, errors.New("invalid patch")
+		}
+		info.SourceSize = int(binary.LittleEndian.Uint32(patch[56:]))
+		offset = 1084
+	case '3':
+		if len(patch) < 60 {
+			return nil, errors.New("invalid patch")
+		}
+		undo = patch[58] != 0
+		offset = 60
+		if patch[57] != 0 {
+			offset += 1024
+		}
+	}
+	addressSize := 4
+	if version == '3' {
+		addressSize = 8
+	}
+	end := ppfEnd(patch, version)
+	for offset+addressSize < end {
+		length := int(patch[offset+addressSize])
+		offset += addressSize + 1 + length
+		if undo {
+			offset += length
+		}
+		info.Records++
+	}
+	if offset != end {
+		return nil, errors.New("invalid patch")
+	}
+	return &info, nil
+}
+
+// ApplyData applies a patch to source, the format is detected from the header.
+// The checksums of the patch are verified when the format has some.
+func ApplyData(patch, source []byte) (*[]byte, error) {
+	info, err := Inspect(patch)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range formats {
+		if f.ext == info.Format {
+			return f.apply(patch, source)
+		}
+	}
+	return nil, errors.New("unknown patch format")
+}
@@ -1,47 +1,178 @@
 // Package patch allows softpatching ROMs based on the presence of a patch file
 // next to the ROM. This is useful to apply fan translations without altering
-// No-Intro ROMs. Softpatching only works for cores where NeedFullPath is false.
+// No-Intro ROMs. For cores where NeedFullPath is true, the patched ROM is
+// written to a cache directory and its path is given to the core. The tracks
+// of disc images described by a cue sheet are patched the same way.
+//
+// Supported formats are IPS, UPS, BPS, PPF and xdelta (VCDIFF).
+//
+// Patches can be stacked: game.ips is applied first, then game.ips1,
+// game.ips2 and so on. Patches can also be picked per game from the patches
+// directory, in that case the choice is kept in the settings.
 package patch
 
 import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
 )
 
-// Try to apply different patches located next to the game
-// Currently only .ups is supported
-func Try(gamePath string, bytes []byte) (*[]byte, error) {
-	upsFile := strings.TrimSuffix(gamePath, filepath.Ext(gamePath)) + ".ups"
-	if _, err := os.Stat(upsFile); !os.IsNotExist(err) {
-		pbytes, err := ioutil.ReadFile(upsFile)
-		if err != nil {
-			return nil, err
-		}
+// None is the per game choice disabling softpatching
+const None = "none"
 
-		patched, err := applyUPS(pbytes, bytes)
-		if err != nil {
-			return nil, err
+// maxTargetSize bounds the size of a patched file, so a corrupt patch can't
+// make us allocate more memory than the biggest disc images need
+const maxTargetSize = 1 << 30
+
+// formats lists the supported patch formats, by order of preference
+var formats = []struct {
+	ext   string
+	apply func(patch, source []byte) (*[]byte, error)
+}{
+	{".bps", applyBPS},
+	{".ups", applyUPS},
+	{".ips", applyIPS},
+	{".ppf", applyPPF},
+	{".xdelta", applyVCDIFF},
+	{".vcdiff", applyVCDIFF},
+}
+
+// apply applies a patch file, the format is guessed from the extension
+func apply(path string, bytes []byte) (*[]byte, error) {
+	ext := strings.TrimRightFunc(filepath.Ext(path), unicode.IsDigit)
+	for _, f := range formats {
+		if f.ext == ext {
+			pbytes, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return f.apply(pbytes, bytes)
 		}
+	}
+	return nil, os.ErrInvalid
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
 
-		return patched, nil
+// stack returns a patch followed by the patches stacked on top of it, like
+// game.ips, game.ips1, game.ips2
+func stack(first string) []string {
+	paths := []string{first}
+	for i := 1; exists(first + strconv.Itoa(i)); i++ {
+		paths = append(paths, first+strconv.Itoa(i))
 	}
+	return paths
+}
 
-	ipsFile := strings.TrimSuffix(gamePath, filepath.Ext(gamePath)) + ".ips"
-	if _, err := os.Stat(ipsFile); !os.IsNotExist(err) {
-		pbytes, err := ioutil.ReadFile(ipsFile)
-		if err != nil {
-			return nil, err
+// Find returns the patches located next to the game, in the order they have
+// to be applied. When the game is an archive, romPath is the path of the
+// extracted ROM: patches named after the ROM are searched next to the archive,
+// then in the archive itself.
+func Find(gamePath, romPath string) []string {
+	candidates := []string{gamePath}
+	if romPath != gamePath {
+		candidates = append(candidates,
+			filepath.Join(filepath.Dir(gamePath), filepath.Base(romPath)),
+			romPath)
+	}
+	for _, candidate := range candidates {
+		base := strings.TrimSuffix(candidate, filepath.Ext(candidate))
+		for _, f := range formats {
+			if exists(base + f.ext) {
+				return stack(base + f.ext)
+			}
 		}
+	}
+	return nil
+}
 
-		patched, err := applyIPS(pbytes, bytes)
+// Directory returns the directory holding the patches of a game
+func Directory(gamePath string) string {
+	return filepath.Join(settings.Current.PatchesDirectory, utils.FileName(gamePath))
+}
+
+// Available lists the patches of the patches directory that can be picked
+// for a game. Stacked patches are not listed, they follow the first patch.
+func Available(gamePath string) []string {
+	files, err := ioutil.ReadDir(Directory(gamePath))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		for _, f := range formats {
+			if !fi.IsDir() && ext == f.ext {
+				paths = append(paths, filepath.Join(Directory(gamePath), fi.Name()))
+			}
+		}
+	}
+	return paths
+}
+
+// Choice returns the patch picked for a game, None if softpatching is
+// disabled, or an empty string to use the patches located next to the game
+func Choice(gamePath string) string {
+	return settings.Current.Softpatches[gamePath]
+}
+
+// SetChoice remembers the patch to apply to a game
+func SetChoice(gamePath, choice string) error {
+	if settings.Current.Softpatches == nil {
+		settings.Current.Softpatches = map[string]string{}
+	}
+	if choice == "" {
+		delete(settings.Current.Softpatches, gamePath)
+	} else {
+		settings.Current.Softpatches[gamePath] = choice
+	}
+	return settings.Save()
+}
+
+// Patches returns the patches to apply to a game, in order
+func Patches(gamePath, romPath string) []string {
+	switch choice := Choice(gamePath); choice {
+	case None:
+		return nil
+	case "":
+		return Find(gamePath, romPath)
+	default:
+		return stack(choice)
+	}
+}
+
+// Apply applies patches in order
+func Apply(paths []string, bytes []byte) (*[]byte, error) {
+	for _, path := range paths {
+		patched, err := apply(path, bytes)
 		if err != nil {
 			return nil, err
 		}
-
-		return patched, nil
+		bytes = *patched
 	}
+	return &bytes, nil
+}
 
-	return nil, nil
+// Try to apply the patches of a game. romPath is the path of the ROM, which
+// differs from gamePath for archived games. It returns the patched game and
+// the paths of the patches applied, or nil if the game has no patch.
+func Try(gamePath, romPath string, bytes []byte) (*[]byte, []string, error) {
+	paths := Patches(gamePath, romPath)
+	if len(paths) == 0 {
+		return nil, nil, nil
+	}
+	patched, err := Apply(paths, bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return patched, paths, nil
 }
@@ -0,0 +1,173 @@
+package patch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/settings"
+)
+
+// ipsPatch writes a single byte at the given address
+func ipsPatch(address int, b byte) []byte {
+	return []byte{'P', 'A', 'T', 'C', 'H',
+		byte(address >> 16), byte(address >> 8), byte(address), 0, 1, b,
+		'E', 'O', 'F'}
+}
+
+func Test_Try(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	game := filepath.Join(dir, "game.sfc")
+	write := func(name string, data []byte) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Returns nil without patch", func(t *testing.T) {
+		got, paths, err := Try(game, game, []byte("abcd"))
+		if got != nil || paths != nil || err != nil {
+			t.Errorf("Try() = %v, %v, %v", got, paths, err)
+		}
+	})
+
+	write("game.ips", ipsPatch(0, 'x'))
+	write("game.ips1", ipsPatch(1, 'y'))
+	write("game.ips2", ipsPatch(3, 'z'))
+	write("game.ips4", ipsPatch(2, 'w'))
+
+	t.Run("Applies stacked patches in order", func(t *testing.T) {
+		got, paths, err := Try(game, game, []byte("abcd"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(*got) != "xycz" {
+			t.Errorf("Try() = %q, want %q", *got, "xycz")
+		}
+		want := []string{
+			filepath.Join(dir, "game.ips"),
+			filepath.Join(dir, "game.ips1"),
+			filepath.Join(dir, "game.ips2"),
+		}
+		if !reflect.DeepEqual(paths, want) {
+			t.Errorf("Try() = %v, want %v", paths, want)
+		}
+	})
+
+	t.Run("Prefers BPS patches", func(t *testing.T) {
+		source, target := []byte("abcd"), []byte("abcde")
+		actions := append(encodeNumber((4-1)<<2|bpsSourceRead), encodeNumber(bpsTargetRead)...)
+		write("game.bps", bpsPatch(source, target, append(actions, 'e')))
+		defer os.Remove(filepath.Join(dir, "game.bps"))
+
+		got, paths, err := Try(game, game, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(*got) != "abcde" || len(paths) != 1 {
+			t.Errorf("Try() = %q, %v", *got, paths)
+		}
+	})
+}
+
+func Test_Find(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	extracted := filepath.Join(dir, "tmp")
+	os.Mkdir(extracted, os.ModePerm)
+	game := filepath.Join(dir, "Game.zip")
+	rom := filepath.Join(extracted, "Game (USA).sfc")
+
+	t.Run("Finds patches inside archives", func(t *testing.T) {
+		ioutil.WriteFile(filepath.Join(extracted, "Game (USA).ups"), []byte{}, 0644)
+		got := Find(game, rom)
+		want := []string{filepath.Join(extracted, "Game (USA).ups")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Find() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Finds patches named after the archived ROM", func(t *testing.T) {
+		ioutil.WriteFile(filepath.Join(dir, "Game (USA).ips"), []byte{}, 0644)
+		got := Find(game, rom)
+		want := []string{filepath.Join(dir, "Game (USA).ips")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Find() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Prefers patches named after the archive", func(t *testing.T) {
+		ioutil.WriteFile(filepath.Join(dir, "Game.ips"), []byte{}, 0644)
+		got := Find(game, rom)
+		want := []string{filepath.Join(dir, "Game.ips")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Find() = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_Fullpath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-patched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings.Current.PatchedDirectory = filepath.Join(dir, "patched")
+
+	rom := filepath.Join(dir, "Game.sfc")
+	ioutil.WriteFile(rom, []byte("game"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "Game.ips"), ipsPatch(0, 'n'), 0644)
+
+	first, patches, err := Fullpath(rom, rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first) != "Game.sfc" || len(patches) != 1 {
+		t.Errorf("Fullpath() = %v, %v, want the name of the ROM", first, patches)
+	}
+	got, _ := ioutil.ReadFile(first)
+	if string(got) != "name" {
+		t.Errorf("Fullpath() wrote %q, want %q", got, "name")
+	}
+
+	t.Run("Reuses the cached ROM", func(t *testing.T) {
+		ioutil.WriteFile(first, []byte("cached"), 0644)
+		path, _, err := Fullpath(rom, rom)
+		if err != nil || path != first {
+			t.Fatalf("Fullpath() = %v, %v, want %v", path, err, first)
+		}
+		got, _ := ioutil.ReadFile(path)
+		if string(got) != "cached" {
+			t.Errorf("Fullpath() patched the ROM again")
+		}
+	})
+
+	t.Run("Replaces the cached ROM when the patch changes", func(t *testing.T) {
+		ioutil.WriteFile(filepath.Join(dir, "Game.ips"), ipsPatch(0, 's'), 0644)
+		second, _, err := Fullpath(rom, rom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second == first {
+			t.Errorf("Fullpath() = %v, want a new path", second)
+		}
+		if exists(first) {
+			t.Errorf("Fullpath() should remove the previous version %v", first)
+		}
+		got, _ := ioutil.ReadFile(second)
+		if string(got) != "same" {
+			t.Errorf("Fullpath() wrote %q, want %q", got, "same")
+		}
+	})
+}
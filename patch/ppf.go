@@ -0,0 +1,117 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// PPF image types, they decide where the block check is located
+const (
+	ppfImageBIN = 0
+	ppfImageGI  = 1
+)
+
+// ppfMaxGrowth is how far past the end of the image a record can write. PPF
+// patches rarely extend images, a record far away means a corrupt patch.
+const ppfMaxGrowth = 1 << 20
+
+// ppfBlockCheck returns the offset of the 1024 bytes used to make sure the
+// patch is applied to the right image
+func ppfBlockCheck(imageType byte) int {
+	if imageType == ppfImageGI {
+		return 0x80a0
+	}
+	return 0x9320
+}
+
+// ppfEnd returns the end of the records, before the optional file_id.diz
+func ppfEnd(patch []byte, version byte) int {
+	begin := bytes.LastIndex(patch, []byte("@BEGIN_FILE_ID.DIZ"))
+	if version < '2' || begin < 0 {
+		return len(patch)
+	}
+	return begin
+}
+
+func applyPPF(patch, source []byte) (*[]byte, error) {
+	if len(patch) < 56 {
+		return nil, errors.New("patch too small")
+	}
+
+	if string(patch[0:3]) != "PPF" || patch[4] != '0' || patch[3] < '1' || patch[3] > '3' {
+		return nil, errors.New("invalid patch header")
+	}
+	version := patch[3]
+
+	offset := 56
+	imageType := byte(ppfImageBIN)
+	blockCheck := false
+	undo := false
+	switch version {
+	case '2':
+		if len(patch) < 1084 {
+			return nil, errors.New("patch too small")
+		}
+		size := binary.LittleEndian.Uint32(patch[56:])
+		if int(size) != len(source) {
+			return nil, errors.New("invalid source")
+		}
+		blockCheck = true
+		offset = 60
+	case '3':
+		if len(patch) < 60 {
+			return nil, errors.New("patch too small")
+		}
+		imageType = patch[56]
+		blockCheck = patch[57] != 0
+		undo = patch[58] != 0
+		offset = 60
+	}
+
+	if blockCheck {
+		if len(patch) < offset+1024 {
+			return nil, errors.New("patch too small")
+		}
+		check := ppfBlockCheck(imageType)
+		if len(source) < check+1024 || !bytes.Equal(source[check:check+1024], patch[offset:offset+1024]) {
+			return nil, errors.New("invalid source")
+		}
+		offset += 1024
+	}
+
+	target := append([]byte{}, source...)
+	end := ppfEnd(patch, version)
+	for offset < end {
+		var address int
+		if version == '3' {
+			if offset+9 > end {
+				return nil, errors.New("invalid patch")
+			}
+			address = int(binary.LittleEndian.Uint64(patch[offset:]))
+			offset += 8
+		} else {
+			if offset+5 > end {
+				return nil, errors.New("invalid patch")
+			}
+			address = int(binary.LittleEndian.Uint32(patch[offset:]))
+			offset += 4
+		}
+		length := int(patch[offset])
+		offset++
+
+		if offset+length > end || address < 0 || address > len(source)+ppfMaxGrowth-length {
+			return nil, errors.New("invalid patch")
+		}
+		if address+length > len(target) {
+			target = append(target, make([]byte, address+length-len(target))...)
+		}
+		copy(target[address:], patch[offset:offset+length])
+		offset += length
+		if undo {
+			offset += length
+		}
+	}
+
+	return &target, nil
+}
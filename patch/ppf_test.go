@@ -0,0 +1,113 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// ppfHeader returns the header of a PPF patch of the given version
+func ppfHeader(version byte) []byte {
+	p := append([]byte("PPF"), version, '0', version-'1')
+	return append(p, bytes.Repeat([]byte(" "), 50)...)
+}
+
+func Test_applyPPF(t *testing.T) {
+	t.Run("PPF1", func(t *testing.T) {
+		p := ppfHeader('1')
+		p = append(p, 2, 0, 0, 0, 2, 'X', 'Y')
+		p = append(p, 8, 0, 0, 0, 1, 'Z') // past the end of the image
+		got, err := applyPPF(p, []byte("abcdef"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(*got) != "abXYef\x00\x00Z" {
+			t.Errorf("applyPPF() = %q", *got)
+		}
+	})
+
+	source := make([]byte, 0x9320+2048)
+	for i := range source {
+		source[i] = byte(i)
+	}
+
+	t.Run("PPF2", func(t *testing.T) {
+		p := ppfHeader('2')
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(source)))
+		p = append(p, size...)
+		p = append(p, source[0x9320:0x9320+1024]...)
+		p = append(p, 1, 0, 0, 0, 1, 'X')
+		p = append(p, []byte("@BEGIN_FILE_ID.DIZ hello @END_FILE_ID.DIZ\x06\x00\x00\x00")...)
+
+		got, err := applyPPF(p, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (*got)[1] != 'X' || len(*got) != len(source) || (*got)[2] != 2 {
+			t.Errorf("applyPPF() = %v", (*got)[:4])
+		}
+
+		_, err = applyPPF(p, source[:len(source)-1])
+		if err == nil || err.Error() != "invalid source" {
+			t.Errorf("applyPPF() = %v, want %v", err, "invalid source")
+		}
+	})
+
+	t.Run("PPF3", func(t *testing.T) {
+		p := ppfHeader('3')
+		p = append(p, ppfImageBIN, 1, 1, 0)
+		p = append(p, source[0x9320:0x9320+1024]...)
+		p = append(p, 3, 0, 0, 0, 0, 0, 0, 0, 2, 'X', 'Y', 3, 4)
+		p = append(p, []byte("@BEGIN_FILE_ID.DIZ hello @END_FILE_ID.DIZ\x06\x00")...)
+
+		got, err := applyPPF(p, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string((*got)[3:5]) != "XY" || (*got)[5] != 5 {
+			t.Errorf("applyPPF() = %v", (*got)[:6])
+		}
+
+		wrong := append([]byte{}, source...)
+		wrong[0x9320] = 0xff
+		_, err = applyPPF(p, wrong)
+		if err == nil || err.Error() != "invalid source" {
+			t.Errorf("applyPPF() = %v, want %v", err, "invalid source")
+		}
+
+		info, err := Inspect(p)
+		if err != nil || info.Format != ".ppf" || info.Records != 1 {
+			t.Errorf("Inspect() = %+v, %v", info, err)
+		}
+	})
+
+	t.Run("Can detect a wrong header", func(t *testing.T) {
+		p := ppfHeader('4')
+		_, err := applyPPF(p, source)
+		if err == nil || err.Error() != "invalid patch header" {
+			t.Errorf("applyPPF() = %v, want %v", err, "invalid patch header")
+		}
+	})
+}
+
+func Test_applyPPF_corrupt(t *testing.T) {
+	t.Run("Rejects a PPF3 record far past the image", func(t *testing.T) {
+		p := ppfHeader('3')
+		p = append(p, ppfImageBIN, 0, 0, 0)
+		p = append(p, 0, 0, 0, 0, 0, 0, 0, 0x20, 1, 'X') // address 1<<61
+		_, err := applyPPF(p, []byte("abcdef"))
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyPPF() = %v, want %v", err, "invalid patch")
+		}
+	})
+
+	t.Run("Rejects a PPF1 record far past the image", func(t *testing.T) {
+		p := ppfHeader('1')
+		p = append(p, 0, 0, 0, 0xff, 1, 'X')
+		_, err := applyPPF(p, []byte("abcdef"))
+		if err == nil || err.Error() != "invalid patch" {
+			t.Errorf("applyPPF() = %v, want %v", err, "invalid patch")
+		}
+	})
+}
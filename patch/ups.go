@@ -52,12 +52,15 @@ func applyUPS(patchData, sourceData []byte) (*[]byte, error) {
 		Data: patchData,
 		Hash: crc32.NewIEEE(),
 	}
+	// Checksums are stored inverted, this is the checksum of an empty file
 	source := &file{
-		Data: sourceData,
-		Hash: crc32.NewIEEE(),
+		Data:     sourceData,
+		Hash:     crc32.NewIEEE(),
+		Checksum: ^uint32(0),
 	}
 	target := &file{
-		Hash: crc32.NewIEEE(),
+		Hash:     crc32.NewIEEE(),
+		Checksum: ^uint32(0),
 	}
 
 	if len(patch.Data) < 18 {
@@ -0,0 +1,345 @@
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/adler32"
+)
+
+// VCDIFF, described in RFC 3284, is the format of the patches produced by
+// xdelta3. Secondary compression and custom code tables are not supported.
+
+// VCDIFF header and window indicators
+const (
+	vcdDecompress = 0x01
+	vcdCodeTable  = 0x02
+	vcdAppHeader  = 0x04 // xdelta3 extension
+
+	vcdSource  = 0x01
+	vcdTarget  = 0x02
+	vcdAdler32 = 0x04 // xdelta3 extension
+)
+
+// VCDIFF instructions
+const (
+	vcdNoop = iota
+	vcdAdd
+	vcdRun
+	vcdCopy
+)
+
+// VCDIFF address cache sizes of the default code table
+const (
+	vcdNear = 4
+	vcdSame = 3
+)
+
+type vcdInstruction struct {
+	kind int
+	size int
+	mode int
+}
+
+// vcdDefaultTable is the default code table, each code holds up to two
+// instructions
+var vcdDefaultTable = func() [256][2]vcdInstruction {
+	var t [256][2]vcdInstruction
+	i := 0
+	t[i][0] = vcdInstruction{vcdRun, 0, 0}
+	i++
+	for size := 0; size <= 17; size++ {
+		t[i][0] = vcdInstruction{vcdAdd, size, 0}
+		i++
+	}
+	for mode := 0; mode <= 8; mode++ {
+		t[i][0] = vcdInstruction{vcdCopy, 0, mode}
+		i++
+		for size := 4; size <= 18; size++ {
+			t[i][0] = vcdInstruction{vcdCopy, size, mode}
+			i++
+		}
+	}
+	for mode := 0; mode <= 5; mode++ {
+		for add := 1; add <= 4; add++ {
+			for size := 4; size <= 6; size++ {
+				t[i] = [2]vcdInstruction{{vcdAdd, add, 0}, {vcdCopy, size, mode}}
+				i++
+			}
+		}
+	}
+	for mode := 6; mode <= 8; mode++ {
+		for add := 1; add <= 4; add++ {
+			t[i] = [2]vcdInstruction{{vcdAdd, add, 0}, {vcdCopy, 4, mode}}
+			i++
+		}
+	}
+	for mode := 0; mode <= 8; mode++ {
+		t[i] = [2]vcdInstruction{{vcdCopy, 4, mode}, {vcdAdd, 1, 0}}
+		i++
+	}
+	return t
+}()
+
+var errVCDIFF = errors.New("invalid patch")
+
+// vcdReader reads the sections of a VCDIFF patch
+type vcdReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *vcdReader) byte() (byte, error) {
+	if r.offset >= len(r.data) {
+		return 0, errVCDIFF
+	}
+	b := r.data[r.offset]
+	r.offset++
+	return b, nil
+}
+
+// int reads a big endian base 128 number
+func (r *vcdReader) int() (int, error) {
+	n := 0
+	for i := 0; i < 9; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			if n < 0 {
+				return 0, errVCDIFF
+			}
+			return n, nil
+		}
+	}
+	return 0, errVCDIFF
+}
+
+func (r *vcdReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errVCDIFF
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+// vcdCache is the address cache used to encode COPY addresses
+type vcdCache struct {
+	near     [vcdNear]int
+	nextSlot int
+	same     [vcdSame * 256]int
+}
+
+func (c *vcdCache) update(addr int) {
+	c.near[c.nextSlot] = addr
+	c.nextSlot = (c.nextSlot + 1) % vcdNear
+	c.same[addr%(vcdSame*256)] = addr
+}
+
+func (c *vcdCache) decode(addrs *vcdReader, here, mode int) (int, error) {
+	var addr int
+	switch {
+	case mode == 0:
+		n, err := addrs.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = n
+	case mode == 1:
+		n, err := addrs.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = here - n
+	case mode-2 < vcdNear:
+		n, err := addrs.int()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.near[mode-2] + n
+	default:
+		b, err := addrs.byte()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.same[(mode-2-vcdNear)*256+int(b)]
+	}
+	if addr < 0 || addr >= here {
+		return 0, errVCDIFF
+	}
+	c.update(addr)
+	return addr, nil
+}
+
+func applyVCDIFF(patch, source []byte) (*[]byte, error) {
+	if len(patch) < 5 {
+		return nil, errors.New("patch too small")
+	}
+
+	if patch[0] != 0xd6 || patch[1] != 0xc3 || patch[2] != 0xc4 || patch[3] != 0 {
+		return nil, errors.New("invalid patch header")
+	}
+
+	r := &vcdReader{data: patch, offset: 5}
+	indicator := patch[4]
+	if indicator&(vcdDecompress|vcdCodeTable) != 0 {
+		return nil, errors.New("unsupported patch: secondary compression or custom code table")
+	}
+	if indicator&vcdAppHeader != 0 {
+		n, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.bytes(n); err != nil {
+			return nil, err
+		}
+	}
+
+	target := []byte{}
+	for r.offset < len(patch) {
+		window, err := vcdWindow(r, source, target)
+		if err != nil {
+			return nil, err
+		}
+		target = append(target, window...)
+	}
+
+	return &target, nil
+}
+
+// vcdWindow decodes a window of the patch, source is the original file and
+// target holds the windows decoded so far
+func vcdWindow(r *vcdReader, source, target []byte) ([]byte, error) {
+	indicator, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	var segment []byte
+	if indicator&(vcdSource|vcdTarget) != 0 {
+		length, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		position, err := r.int()
+		if err != nil {
+			return nil, err
+		}
+		from := source
+		if indicator&vcdTarget != 0 {
+			from = target
+		}
+		if length > len(from) || position > len(from)-length {
+			return nil, errors.New("invalid source")
+		}
+		segment = from[position : position+length]
+	}
+
+	if _, err := r.int(); err != nil { // length of the delta encoding
+		return nil, err
+	}
+	windowLength, err := r.int()
+	if err != nil {
+		return nil, err
+	}
+	// xdelta3 windows are at most 16MB, don't trust bigger lengths
+	if windowLength > 1<<24 {
+		return nil, errVCDIFF
+	}
+	deltaIndicator, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if deltaIndicator != 0 {
+		return nil, errors.New("unsupported patch: compressed sections")
+	}
+	var lengths [3]int
+	for i := range lengths {
+		if lengths[i], err = r.int(); err != nil {
+			return nil, err
+		}
+	}
+	var checksum []byte
+	if indicator&vcdAdler32 != 0 {
+		if checksum, err = r.bytes(4); err != nil {
+			return nil, err
+		}
+	}
+	data, err := r.bytes(lengths[0])
+	if err != nil {
+		return nil, err
+	}
+	inst, err := r.bytes(lengths[1])
+	if err != nil {
+		return nil, err
+	}
+	addr, err := r.bytes(lengths[2])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, windowLength)
+	datas := &vcdReader{data: data}
+	insts := &vcdReader{data: inst}
+	addrs := &vcdReader{data: addr}
+	cache := &vcdCache{}
+
+	for insts.offset < len(inst) {
+		code, _ := insts.byte()
+		for _, in := range vcdDefaultTable[code] {
+			if in.kind == vcdNoop {
+				continue
+			}
+			size := in.size
+			if size == 0 {
+				if size, err = insts.int(); err != nil {
+					return nil, err
+				}
+			}
+			if len(out)+size > windowLength {
+				return nil, errVCDIFF
+			}
+
+			switch in.kind {
+			case vcdAdd:
+				b, err := datas.bytes(size)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, b...)
+			case vcdRun:
+				b, err := datas.byte()
+				if err != nil {
+					return nil, err
+				}
+				for ; size > 0; size-- {
+					out = append(out, b)
+				}
+			case vcdCopy:
+				a, err := cache.decode(addrs, len(segment)+len(out), in.mode)
+				if err != nil {
+					return nil, err
+				}
+				// Copies from the window can overlap the bytes being written
+				for ; size > 0; size-- {
+					if a < len(segment) {
+						out = append(out, segment[a])
+					} else {
+						out = append(out, out[a-len(segment)])
+					}
+					a++
+				}
+			}
+		}
+	}
+
+	if len(out) != windowLength {
+		return nil, errVCDIFF
+	}
+	if checksum != nil && adler32.Checksum(out) != binary.BigEndian.Uint32(checksum) {
+		return nil, errors.New("invalid target")
+	}
+	return out, nil
+}
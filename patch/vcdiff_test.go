@@ -0,0 +1,73 @@
+package patch
+
+import (
+	"encoding/binary"
+	"hash/adler32"
+	"testing"
+)
+
+func Test_applyVCDIFF(t *testing.T) {
+	source := []byte("abcdefgh")
+	target := []byte("abcdXYZabcdZZZZZ")
+
+	// COPY 4 bytes from the source, ADD 3 bytes, COPY 4 bytes addressed from
+	// the current position, RUN of 5 bytes
+	data := []byte("XYZZ")
+	inst := []byte{20, 4, 36, 0, 5}
+	addr := []byte{0, 15}
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, adler32.Checksum(target))
+
+	window := func(checksum []byte) []byte {
+		w := []byte{vcdSource | vcdAdler32, byte(len(source)), 0}
+		w = append(w, 20, byte(len(target)), 0, byte(len(data)), byte(len(inst)), byte(len(addr)))
+		w = append(w, checksum...)
+		w = append(w, data...)
+		w = append(w, inst...)
+		return append(w, addr...)
+	}
+	header := []byte{0xd6, 0xc3, 0xc4, 0, vcdAppHeader, 3, 'l', 'u', 'd'}
+
+	t.Run("Can apply a valid patch", func(t *testing.T) {
+		got, err := applyVCDIFF(append(header, window(checksum)...), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(*got) != string(target) {
+			t.Errorf("applyVCDIFF() = %q, want %q", *got, target)
+		}
+	})
+
+	t.Run("Can detect a wrong checksum", func(t *testing.T) {
+		_, err := applyVCDIFF(append(header, window([]byte{1, 2, 3, 4})...), source)
+		if err == nil || err.Error() != "invalid target" {
+			t.Errorf("applyVCDIFF() = %v, want %v", err, "invalid target")
+		}
+	})
+
+	t.Run("Can detect a short source", func(t *testing.T) {
+		_, err := applyVCDIFF(append(header, window(checksum)...), source[:4])
+		if err == nil || err.Error() != "invalid source" {
+			t.Errorf("applyVCDIFF() = %v, want %v", err, "invalid source")
+		}
+	})
+
+	t.Run("Rejects secondary compression", func(t *testing.T) {
+		_, err := applyVCDIFF([]byte{0xd6, 0xc3, 0xc4, 0, vcdDecompress, 1}, source)
+		if err == nil {
+			t.Errorf("applyVCDIFF() should fail")
+		}
+	})
+	t.Run("Rejects a source segment overflowing int", func(t *testing.T) {
+		p := append([]byte{}, header...)
+		p = append(p, vcdSource)
+		// length and position of 2^62, each 9 bytes of 7 bits
+		huge := []byte{0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0}
+		p = append(p, huge...)
+		p = append(p, huge...)
+		_, err := applyVCDIFF(p, source)
+		if err == nil || err.Error() != "invalid source" {
+			t.Errorf("applyVCDIFF() = %v, want %v", err, "invalid source")
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libretro/ludo/patch"
+)
+
+const patchUsage = `Usage:
+  %[1]s patch create [--format bps|ups|ips] original modified out
+  %[1]s patch apply patch original out
+  %[1]s patch info patch [original]
+`
+
+// runPatch implements the patch subcommand, to create, apply and inspect
+// IPS, UPS and BPS patches
+func runPatch(args []string) error {
+	if len(args) == 0 {
+		fmt.Printf(patchUsage, os.Args[0])
+		return errors.New("missing command")
+	}
+
+	switch args[0] {
+	case "create":
+		return patchCreate(args[1:])
+	case "apply":
+		return patchApply(args[1:])
+	case "info":
+		return patchInfo(args[1:])
+	}
+	fmt.Printf(patchUsage, os.Args[0])
+	return errors.New("unknown command " + args[0])
+}
+
+func patchCreate(args []string) error {
+	fs := flag.NewFlagSet("patch create", flag.ExitOnError)
+	format := fs.String("format", "", "Patch format: bps, ups or ips. Guessed from the output file by default")
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fmt.Printf(patchUsage, os.Args[0])
+		return errors.New("wrong number of arguments")
+	}
+
+	ext := "." + strings.TrimPrefix(strings.ToLower(*format), ".")
+	if *format == "" {
+		ext = strings.ToLower(filepath.Ext(fs.Arg(2)))
+	}
+
+	source, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	target, err := ioutil.ReadFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	p, err := patch.Create(ext, source, target)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the patch gives back the modified file before writing it
+	patched, err := patch.ApplyData(p, source)
+	if err != nil {
+		return err
+	}
+	if string(*patched) != string(target) {
+		return errors.New("the patch doesn't reproduce the modified file")
+	}
+
+	return ioutil.WriteFile(fs.Arg(2), p, 0644)
+}
+
+func patchApply(args []string) error {
+	if len(args) != 3 {
+		fmt.Printf(patchUsage, os.Args[0])
+		return errors.New("wrong number of arguments")
+	}
+
+	p, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	source, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	patched, err := patch.ApplyData(p, source)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(args[2], *patched, 0644)
+}
+
+func patchInfo(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		fmt.Printf(patchUsage, os.Args[0])
+		return errors.New("wrong number of arguments")
+	}
+
+	p, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	info, err := patch.Inspect(p)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Format:   %s\n", strings.ToUpper(strings.TrimPrefix(info.Format, ".")))
+	switch info.Format {
+	case ".ips", ".ppf":
+		fmt.Printf("Records:  %d\n", info.Records)
+		if info.TargetSize > 0 {
+			fmt.Printf("Truncate: %d bytes\n", info.TargetSize)
+		}
+		if info.SourceSize > 0 {
+			fmt.Printf("Original: %d bytes\n", info.SourceSize)
+		}
+	case ".bps", ".ups":
+		fmt.Printf("Original: %d bytes, CRC32 %08x\n", info.SourceSize, info.SourceCRC)
+		fmt.Printf("Modified: %d bytes, CRC32 %08x\n", info.TargetSize, info.TargetCRC)
+		status := "valid"
+		if !info.Valid {
+			status = "corrupted"
+		}
+		fmt.Printf("Patch:    CRC32 %08x, %s\n", info.PatchCRC, status)
+	}
+
+	if len(args) == 2 {
+		source, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		if _, err := patch.ApplyData(p, source); err != nil {
+			return fmt.Errorf("the patch doesn't apply to %s: %v", args[1], err)
+		}
+		fmt.Printf("The patch applies to %s\n", args[1])
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package playlists
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of the playlist format written by Ludo.
+//
+// Version 1 playlists have no header, their columns are the path, name, CRC32
+// and optionally the core path.
+//
+// Version 2 playlists start with a header line, "#ludo-playlist" followed by
+// the version, and add the region, genre, release year, developer and the
+// last played timestamp of the game.
+const Version = 2
+
+// headerTag starts the header line of the playlists since version 2
+const headerTag = "#ludo-playlist"
+
+// header returns the header line of the playlists
+func header() []string {
+	return []string{headerTag, strconv.Itoa(Version)}
+}
+
+// parseHeader returns the version of a playlist from its first line, and if
+// this line is a header
+func parseHeader(line []string) (int, bool) {
+	if len(line) < 2 || line[0] != headerTag {
+		return 1, false
+	}
+	version, err := strconv.Atoi(line[1])
+	if err != nil {
+		log.Println(err)
+		return Version, true
+	}
+	return version, true
+}
+
+// column returns a column of a line, or an empty string if it is missing
+func column(line []string, i int) string {
+	if i < len(line) {
+		return line[i]
+	}
+	return ""
+}
+
+// parseGame reads a line of a playlist. The columns of version 1 are the first
+// ones of version 2, so both are read the same way.
+func parseGame(line []string) Game {
+	game := Game{
+		Path:      filepath.Clean(column(line, 0)),
+		Name:      column(line, 1),
+		CorePath:  column(line, 3),
+		Region:    column(line, 4),
+		Genre:     column(line, 5),
+		Year:      column(line, 6),
+		Developer: column(line, 7),
+	}
+	if crc := column(line, 2); crc != "" {
+		u64, err := strconv.ParseUint(crc, 16, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			game.CRC32 = uint32(u64)
+		}
+	}
+	if played := column(line, 8); played != "" {
+		sec, err := strconv.ParseInt(played, 10, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			game.LastPlayed = time.Unix(sec, 0)
+		}
+	}
+	return game
+}
+
+// formatGame returns the columns of a game in a playlist
+func formatGame(game Game) []string {
+	played := ""
+	if !game.LastPlayed.IsZero() {
+		played = strconv.FormatInt(game.LastPlayed.Unix(), 10)
+	}
+	return []string{
+		game.Path,
+		game.Name,
+		strconv.FormatUint(uint64(game.CRC32), 16),
+		game.CorePath,
+		game.Region,
+		game.Genre,
+		game.Year,
+		game.Developer,
+		played,
+	}
+}
+
+// migrate upgrades the games of an older playlist. The region of the games is
+// guessed from their name, the metadata from the database will be added by
+// the next scan. The old file is kept as a backup.
+func migrate(path string, version int, playlist Playlist) {
+	for i := range playlist {
+		if playlist[i].Region == "" {
+			playlist[i].Region = Region(playlist[i].Name)
+		}
+	}
+	backup := path + ".v" + strconv.Itoa(version)
+	if err := os.Rename(path, backup); err != nil {
+		log.Println(err)
+		return
+	}
+	Playlists[path] = playlist
+	save(path)
+	log.Printf("[Playlists]: Migrated %s to version %d, backup in %s\n", path, Version, backup)
+}
+
+// regionTag matches the first tag of a game name, which is the region in
+// No-Intro and Redump names, like "USA, Europe" in "Game (USA, Europe) (Rev 1)"
+var regionTag = regexp.MustCompile(`\(([^\)]*)\)`)
+
+// Region returns the region of a game from its name
+func Region(name string) string {
+	m := regionTag.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
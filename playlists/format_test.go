@@ -0,0 +1,110 @@
+package playlists
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libretro/ludo/settings"
+)
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings.Current.PlaylistsDirectory = dir
+	Playlists = map[string]Playlist{}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	path := filepath.Join(dir, "Nintendo - SNES.csv")
+	v1 := "/roms/b.sfc\tB (Europe)\t2\n/roms/a.sfc\tA (USA) (Rev 1)\t1\t/cores/snes9x_libretro.so\n"
+	ioutil.WriteFile(path, []byte(v1), 0644)
+
+	Load()
+
+	want := Playlist{
+		{Path: "/roms/a.sfc", Name: "A (USA) (Rev 1)", CRC32: 1, CorePath: "/cores/snes9x_libretro.so", Region: "USA"},
+		{Path: "/roms/b.sfc", Name: "B (Europe)", CRC32: 2, Region: "Europe"},
+	}
+	if got := Get(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	t.Run("Keeps a backup", func(t *testing.T) {
+		backup, err := ioutil.ReadFile(path + ".v1")
+		if err != nil || string(backup) != v1 {
+			t.Errorf("backup = %q, %v", backup, err)
+		}
+	})
+
+	t.Run("Writes the new version", func(t *testing.T) {
+		data, _ := ioutil.ReadFile(path)
+		if !strings.HasPrefix(string(data), "#ludo-playlist\t2\n") {
+			t.Errorf("got %q", data)
+		}
+		Playlists = map[string]Playlist{}
+		Load()
+		if got := Get(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		if _, err := os.Stat(path + ".v2"); !os.IsNotExist(err) {
+			t.Errorf("up to date playlists should not be migrated")
+		}
+	})
+}
+
+func TestEdit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-edit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.csv")
+	Playlists = map[string]Playlist{
+		path: {{Path: "/roms/a.sfc", Name: `A "Quoted" (USA)`, Genre: "Action"}},
+	}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	if !Update(path, Game{Path: "/roms/a.sfc", Genre: "Puzzle", Year: "1994"}) {
+		t.Error("Update() = false, want true")
+	}
+	if Update(path, Game{Path: "/roms/other.sfc", Year: "1994"}) {
+		t.Error("Update() = true, want false")
+	}
+	SetCore(path, "/roms/a.sfc", "/cores/bsnes_libretro.so")
+	before := time.Now().Add(-time.Second)
+	Touch(path, "/roms/a.sfc")
+
+	game := Get(path)[0]
+	if game.Genre != "Action" || game.Year != "1994" || game.CorePath != "/cores/bsnes_libretro.so" || game.LastPlayed.Before(before) {
+		t.Errorf("got %+v", game)
+	}
+
+	// The changes were saved
+	settings.Current.PlaylistsDirectory = dir
+	Playlists = map[string]Playlist{}
+	Load()
+	saved := Get(path)[0]
+	if saved.Name != game.Name || saved.CorePath != game.CorePath || saved.LastPlayed.Unix() != game.LastPlayed.Unix() {
+		t.Errorf("got %+v, want %+v", saved, game)
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := map[string]string{
+		"Game (USA, Europe) (Rev 1)": "USA, Europe",
+		"Game (Japan)":               "Japan",
+		"Game":                       "",
+	}
+	for name, want := range tests {
+		if got := Region(name); got != want {
+			t.Errorf("Region(%v) = %v, want %v", name, got, want)
+		}
+	}
+}
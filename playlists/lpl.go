@@ -0,0 +1,165 @@
+package playlists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+// lplDetect is the value RetroArch uses when the core or the CRC is unknown
+const lplDetect = "DETECT"
+
+// lpl is a RetroArch playlist, in the JSON format introduced in RetroArch 1.7.6
+type lpl struct {
+	Version         string    `json:"version"`
+	DefaultCorePath string    `json:"default_core_path"`
+	DefaultCoreName string    `json:"default_core_name"`
+	Items           []lplItem `json:"items"`
+}
+
+type lplItem struct {
+	Path     string `json:"path"`
+	Label    string `json:"label"`
+	CorePath string `json:"core_path"`
+	CoreName string `json:"core_name"`
+	CRC32    string `json:"crc32"`
+	DBName   string `json:"db_name"`
+}
+
+// Rewrite moves the paths of the games from one base directory to another,
+// like when importing playlists made on another computer. Paths outside of
+// From are left untouched, and the zero Rewrite doesn't change anything.
+type Rewrite struct {
+	From string
+	To   string
+}
+
+// Apply rewrites a path. The separators are compared loosely, so Windows
+// paths can be rewritten to Unix paths and the other way around.
+func (r Rewrite) Apply(path string) string {
+	if r.From == "" {
+		return path
+	}
+	slashed := strings.Replace(path, `\`, "/", -1)
+	from := strings.TrimSuffix(strings.Replace(r.From, `\`, "/", -1), "/")
+	if slashed != from && !strings.HasPrefix(slashed, from+"/") {
+		return path
+	}
+	return filepath.Join(r.To, filepath.FromSlash(strings.TrimPrefix(slashed, from)))
+}
+
+// parseLPLCRC reads the checksums of RetroArch playlists, like 1234ABCD|crc
+func parseLPLCRC(crc string) uint32 {
+	crc = strings.SplitN(crc, "|", 2)[0]
+	u64, err := strconv.ParseUint(crc, 16, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(u64)
+}
+
+// lplSystem returns the system of a playlist item from its db_name. Names that
+// would lead out of the playlists directory are rejected.
+func lplSystem(dbName string) (string, bool) {
+	system := strings.TrimSuffix(dbName, ".lpl")
+	if system == "" || system == "." || system == ".." ||
+		strings.ContainsAny(system, `/\`) || system != filepath.Base(system) {
+		return "", false
+	}
+	return system, true
+}
+
+// Import adds the games of a RetroArch playlist to the Ludo playlists. The
+// games are sorted in playlists by their db_name, or the name of the RetroArch
+// playlist. The playlists that changed are saved. It returns the number of
+// games added.
+func Import(lplPath string, rewrite Rewrite) (int, error) {
+	data, err := ioutil.ReadFile(lplPath)
+	if err != nil {
+		return 0, err
+	}
+	var l lpl
+	if err := json.Unmarshal(data, &l); err != nil {
+		return 0, err
+	}
+
+	added := 0
+	changed := map[string]bool{}
+	for _, item := range l.Items {
+		if item.Path == "" {
+			continue
+		}
+		system := utils.FileName(lplPath)
+		if item.DBName != "" {
+			var ok bool
+			if system, ok = lplSystem(item.DBName); !ok {
+				log.Println("[Playlists]: Invalid db_name", item.DBName)
+				continue
+			}
+		}
+		name := item.Label
+		if name == "" {
+			name = utils.FileName(item.Path)
+		}
+		corePath := item.CorePath
+		if corePath == lplDetect || corePath == l.DefaultCorePath {
+			corePath = ""
+		}
+		CSVPath := filepath.Join(settings.Current.PlaylistsDirectory, system+".csv")
+		if Add(CSVPath, Game{
+			Path:     filepath.Clean(rewrite.Apply(item.Path)),
+			Name:     name,
+			CRC32:    parseLPLCRC(item.CRC32),
+			CorePath: corePath,
+			Region:   Region(name),
+		}) {
+			changed[CSVPath] = true
+			added++
+		}
+	}
+	for path := range changed {
+		Save(path)
+	}
+	return added, nil
+}
+
+// Export writes a Ludo playlist as a RetroArch playlist
+func Export(CSVPath, lplPath string, rewrite Rewrite) error {
+	system := utils.FileName(CSVPath)
+	l := lpl{Version: "1.5", Items: []lplItem{}}
+	for _, game := range Get(CSVPath) {
+		item := lplItem{
+			Path:     rewrite.Apply(game.Path),
+			Label:    game.Name,
+			CorePath: lplDetect,
+			CoreName: lplDetect,
+			CRC32:    lplDetect,
+			DBName:   system + ".lpl",
+		}
+		if game.CorePath != "" {
+			item.CorePath = game.CorePath
+			item.CoreName = utils.FileName(game.CorePath)
+		}
+		if game.CRC32 != 0 {
+			item.CRC32 = fmt.Sprintf("%08X|crc", game.CRC32)
+		}
+		l.Items = append(l.Items, item)
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(lplPath), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(lplPath, data, 0644)
+}
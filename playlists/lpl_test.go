@@ -0,0 +1,137 @@
+package playlists
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/settings"
+)
+
+const testLPL = `{
+  "version": "1.5",
+  "default_core_path": "/cores/snes9x_libretro.so",
+  "default_core_name": "Snes9x",
+  "items": [
+    {
+      "path": "C:\\Games\\snes\\Game (USA).sfc",
+      "label": "Game (USA)",
+      "core_path": "DETECT",
+      "core_name": "DETECT",
+      "crc32": "1234ABCD|crc",
+      "db_name": "Nintendo - Super Nintendo Entertainment System.lpl"
+    },
+    {
+      "path": "/roms/other.sfc",
+      "label": "",
+      "core_path": "/cores/bsnes_libretro.so",
+      "core_name": "bsnes",
+      "crc32": "DETECT",
+      "db_name": ""
+    }
+  ]
+}`
+
+func TestRewrite_Apply(t *testing.T) {
+	tests := []struct {
+		rewrite Rewrite
+		path    string
+		want    string
+	}{
+		{Rewrite{}, "/roms/a.sfc", "/roms/a.sfc"},
+		{Rewrite{"/roms", "/games"}, "/roms/snes/a.sfc", filepath.Join("/games", "snes", "a.sfc")},
+		{Rewrite{"/roms/", "/games"}, "/roms/a.sfc", filepath.Join("/games", "a.sfc")},
+		{Rewrite{"/roms", "/games"}, "/roms2/a.sfc", "/roms2/a.sfc"},
+		{Rewrite{`C:\Games`, "/games"}, `C:\Games\snes\a.sfc`, filepath.Join("/games", "snes", "a.sfc")},
+	}
+	for _, tt := range tests {
+		if got := tt.rewrite.Apply(tt.path); got != tt.want {
+			t.Errorf("Apply(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-lpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings.Current.PlaylistsDirectory = dir
+	Playlists = map[string]Playlist{}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	lplPath := filepath.Join(dir, "Favorites.lpl")
+	ioutil.WriteFile(lplPath, []byte(testLPL), 0644)
+
+	added, err := Import(lplPath, Rewrite{From: `C:\Games`, To: "/home/me/roms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("Import() = %v, want %v", added, 2)
+	}
+
+	snes := filepath.Join(dir, "Nintendo - Super Nintendo Entertainment System.csv")
+	want := Playlist{{
+		Path:   filepath.Join("/home/me/roms", "snes", "Game (USA).sfc"),
+		Name:   "Game (USA)",
+		CRC32:  0x1234abcd,
+		Region: "USA",
+	}}
+	if got := Get(snes); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	favorites := filepath.Join(dir, "Favorites.csv")
+	want = Playlist{{Path: "/roms/other.sfc", Name: "other", CorePath: "/cores/bsnes_libretro.so"}}
+	if got := Get(favorites); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	t.Run("Saves the imported playlists", func(t *testing.T) {
+		Playlists = map[string]Playlist{}
+		Load()
+		if Count(snes) != 1 || !reflect.DeepEqual(Get(favorites), want) {
+			t.Errorf("Load() = %+v", Playlists)
+		}
+	})
+
+	t.Run("Exports back", func(t *testing.T) {
+		exported := filepath.Join(dir, "export", "Favorites.lpl")
+		if err := Export(favorites, exported, Rewrite{From: "/roms", To: "/mnt/roms"}); err != nil {
+			t.Fatal(err)
+		}
+		Playlists = map[string]Playlist{}
+		if added, err := Import(exported, Rewrite{From: "/mnt/roms", To: "/roms"}); err != nil || added != 1 {
+			t.Fatalf("Import() = %v, %v", added, err)
+		}
+		if got := Get(favorites); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
+
+func Test_lplSystem(t *testing.T) {
+	tests := []struct {
+		dbName string
+		want   string
+		ok     bool
+	}{
+		{"Nintendo - Game Boy.lpl", "Nintendo - Game Boy", true},
+		{"Nintendo - Game Boy", "Nintendo - Game Boy", true},
+		{"../../.bashrc", "", false},
+		{"..", "", false},
+		{"...lpl", "", false},
+		{"snes/../../x.lpl", "", false},
+		{`..\..\x.lpl`, "", false},
+		{"/etc/passwd", "", false},
+		{".lpl", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := lplSystem(tt.dbName); got != tt.want || ok != tt.ok {
+			t.Errorf("lplSystem(%q) = %q, %v, want %q, %v", tt.dbName, got, ok, tt.want, tt.ok)
+		}
+	}
+}
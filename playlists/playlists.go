@@ -1,17 +1,20 @@
 // Package playlists is the playlist manager of Ludo. In Ludo, playlists are
-// CSV files containing the ROM path, name, and CRC32 checksum.
+// CSV files containing the ROM path, name, CRC32 checksum, core override and
+// metadata of the games. RetroArch playlists can be imported and exported.
 // Playlists are kept into memory for fast lookup of entries and deduplication.
 package playlists
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
+	"sync"
+	"time"
 
 	"github.com/libretro/ludo/settings"
 )
@@ -21,14 +24,29 @@ type Game struct {
 	Path  string // Absolute path of the game on the filesystem
 	Name  string // Human readable name of the game, comes from the RDB
 	CRC32 uint32 // Checksum of the game, used for deduplication
+
+	CorePath string // Core used for this game, overrides the core of the playlist
+
+	// Metadata from the database
+	Region    string
+	Genre     string
+	Year      string // Release year
+	Developer string
+
+	LastPlayed time.Time // Zero if the game was never played from the playlist
 }
 
 // Playlist is a list of games, result of scanning for games on the filesystem.
 type Playlist []Game
 
-// Playlists is a map of playlists organized per system.
+// Playlists is a map of playlists organized per system. The scanner updates it
+// from its own goroutine, so it should be accessed through the functions of
+// this package.
 var Playlists = map[string]Playlist{}
 
+// mutex guards Playlists
+var mutex sync.RWMutex
+
 // Gets a list of full paths to playlists
 func getPaths() (paths []string) {
 	paths, err := filepath.Glob(settings.Current.PlaylistsDirectory + "/*.csv")
@@ -41,20 +59,22 @@ func getPaths() (paths []string) {
 // Load loops over lpl files in the playlists directory and loads them into
 // memory.
 func Load() {
+	mutex.Lock()
+	defer mutex.Unlock()
 	for _, path := range getPaths() {
-		path := path
-
 		file, err := os.Open(path)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
-		defer file.Close()
 		reader := csv.NewReader(bufio.NewReader(file))
 		reader.Comma = '\t'
+		reader.FieldsPerRecord = -1 // older versions have less columns
+		reader.LazyQuotes = true
 
 		playlist := Playlist{}
-		for {
+		version := 1
+		for first := true; ; first = false {
 			line, err := reader.Read()
 			if err == io.EOF {
 				break
@@ -62,30 +82,81 @@ func Load() {
 				log.Println(err)
 				continue
 			}
-			var entry Game
-			entry.Path = filepath.Clean(line[0])
-			entry.Name = line[1]
-			if line[2] != "" {
-				u64, err := strconv.ParseUint(line[2], 16, 64)
-				if err != nil {
-					log.Println(err)
-				} else {
-					entry.CRC32 = uint32(u64)
+			if first {
+				var isHeader bool
+				if version, isHeader = parseHeader(line); isHeader {
+					continue
 				}
 			}
-
-			playlist = append(playlist, entry)
+			playlist = append(playlist, parseGame(line))
 		}
+		file.Close()
 		sort.Slice(playlist, func(i, j int) bool {
 			return playlist[i].Name < playlist[j].Name
 		})
 		Playlists[path] = playlist
+		if version < Version {
+			migrate(path, version, playlist)
+		}
+	}
+}
+
+// Paths returns the paths of the playlists in memory, sorted
+func Paths() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	var paths []string
+	for path := range Playlists {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Get returns a copy of a playlist
+func Get(path string) Playlist {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return append(Playlist{}, Playlists[filepath.Clean(path)]...)
+}
+
+// Find returns the game of a playlist having this path
+func Find(CSVPath, path string) (Game, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	for _, game := range Playlists[filepath.Clean(CSVPath)] {
+		if filepath.Clean(game.Path) == filepath.Clean(path) {
+			return game, true
+		}
 	}
+	return Game{}, false
+}
+
+// Set replaces a playlist in memory and saves it
+func Set(path string, playlist Playlist) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	path = filepath.Clean(path)
+	Playlists[path] = playlist
+	save(path)
+}
+
+// Remove forgets a playlist, its file is left untouched
+func Remove(path string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	delete(Playlists, filepath.Clean(path))
 }
 
 // Contains checks if a game is already in a playlist.
 func Contains(CSVPath, path string, CRC32 uint32) bool {
-	for _, entry := range Playlists[filepath.Clean(CSVPath)] {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return contains(filepath.Clean(CSVPath), path, CRC32)
+}
+
+func contains(CSVPath, path string, CRC32 uint32) bool {
+	for _, entry := range Playlists[CSVPath] {
 		// Be careful, sometimes we don't have a CRC32
 		if filepath.Clean(entry.Path) == filepath.Clean(path) || (CRC32 != 0 && entry.CRC32 == CRC32) {
 			return true
@@ -94,20 +165,165 @@ func Contains(CSVPath, path string, CRC32 uint32) bool {
 	return false
 }
 
+// Add inserts a game in a playlist in memory, keeping it sorted by name. It
+// returns false if the game was already there. The playlist is not saved.
+func Add(CSVPath string, game Game) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	CSVPath = filepath.Clean(CSVPath)
+	if contains(CSVPath, game.Path, game.CRC32) {
+		return false
+	}
+	playlist := Playlists[CSVPath]
+	i := sort.Search(len(playlist), func(i int) bool {
+		return playlist[i].Name > game.Name
+	})
+	playlist = append(playlist, Game{})
+	copy(playlist[i+1:], playlist[i:])
+	playlist[i] = game
+	Playlists[CSVPath] = playlist
+	return true
+}
+
+// Update fills the metadata missing from the game of a playlist having the
+// same path. It returns true if the game changed. The playlist is not saved.
+func Update(CSVPath string, game Game) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	changed := false
+	edit(filepath.Clean(CSVPath), game.Path, func(g *Game) {
+		fill := func(field *string, value string) {
+			if *field == "" && value != "" {
+				*field = value
+				changed = true
+			}
+		}
+		fill(&g.Region, game.Region)
+		fill(&g.Genre, game.Genre)
+		fill(&g.Year, game.Year)
+		fill(&g.Developer, game.Developer)
+	})
+	return changed
+}
+
+// SetCore sets the core of a game of a playlist, overriding the core of the
+// playlist. An empty corePath removes the override. The playlist is saved.
+func SetCore(CSVPath, path, corePath string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	CSVPath = filepath.Clean(CSVPath)
+	if edit(CSVPath, path, func(g *Game) { g.CorePath = corePath }) {
+		save(CSVPath)
+	}
+}
+
+// Touch records that a game of a playlist was just played. The playlist is
+// saved.
+func Touch(CSVPath, path string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	CSVPath = filepath.Clean(CSVPath)
+	if edit(CSVPath, path, func(g *Game) { g.LastPlayed = time.Now() }) {
+		save(CSVPath)
+	}
+}
+
+// edit calls cb with the game of a playlist having this path. It returns
+// false if the game is not in the playlist.
+func edit(CSVPath, path string, cb func(g *Game)) bool {
+	playlist := Playlists[CSVPath]
+	for i := range playlist {
+		if filepath.Clean(playlist[i].Path) == filepath.Clean(path) {
+			cb(&playlist[i])
+			return true
+		}
+	}
+	return false
+}
+
+// Prune removes the games rejected by keep from the playlists, like games
+// whose file disappeared. The playlists are saved if they changed. It returns
+// the number of games removed.
+func Prune(keep func(path string) bool) int {
+	mutex.Lock()
+	defer mutex.Unlock()
+	removed := 0
+	for path, playlist := range Playlists {
+		kept := Playlist{}
+		for _, game := range playlist {
+			if keep(game.Path) {
+				kept = append(kept, game)
+			}
+		}
+		if len(kept) != len(playlist) {
+			removed += len(playlist) - len(kept)
+			Playlists[path] = kept
+			save(path)
+		}
+	}
+	return removed
+}
+
 // Count is a quick way of knowing how many games are in a playlist
 func Count(path string) int {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return len(Playlists[filepath.Clean(path)])
 }
 
 // Save will write a playlist to the filesystem
 func Save(path string) {
-	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0644)
-	defer f.Close()
+	mutex.Lock()
+	defer mutex.Unlock()
+	save(filepath.Clean(path))
+}
+
+// save writes a playlist to a temporary file and renames it, so the playlist
+// is never left half written. The caller must hold the write lock.
+func save(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		log.Println(err)
+		return
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = '\t'
+	w.Write(header())
 	for _, game := range Playlists[path] {
-		f.WriteString(game.Path + "\t")
-		f.WriteString(game.Name + "\t")
-		f.WriteString(strconv.FormatUint(uint64(game.CRC32), 16) + "\n")
+		w.Write(formatGame(game))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := writeAtomic(path, buf.Bytes()); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeAtomic writes data to a temporary file and renames it
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	fd, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(tmp)
+		return err
 	}
+	return os.Rename(tmp, path)
 }
 
 // ShortName shortens the name of some game systems that are too long to be
@@ -1,12 +1,18 @@
 // Package playlists is the playlist manager of Ludo. In Ludo, playlists are
-// CSV files containing the ROM path, name, and CRC32 checksum.
+// CSV files containing the ROM path, name, CRC32 checksum, core override and
+// metadata of the games. RetroArch playlists can be imported and exported.
 // Playlists are kept into memory for fast lookup of entries and deduplication.
 package playlists
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/libretro/ludo/settings"
 )
@@ -21,19 +27,30 @@ func TestLoad(t *testing.T) {
 		want := map[string]Playlist{
 			filepath.Join("testdata", "Sega - Master System - Mark III.csv"): Playlist{
 				{
-					filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Aleste (Japan).zip"),
-					"Aleste (Japan)",
-					3636729435,
+					Path:      filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Aleste (Japan).zip"),
+					Name:      "Aleste (Japan)",
+					CRC32:     3636729435,
+					Region:    "Japan",
+					Genre:     "Shooter",
+					Year:      "1988",
+					Developer: "Compile",
 				},
 				{
-					filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Alex Kidd in Miracle World (USA, Europe) (Rev 1).zip"),
-					"Alex Kidd in Miracle World (USA, Europe, Brazil) (Rev 1)",
-					2933500612,
+					Path:       filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Alex Kidd in Miracle World (USA, Europe) (Rev 1).zip"),
+					Name:       "Alex Kidd in Miracle World (USA, Europe, Brazil) (Rev 1)",
+					CRC32:      2933500612,
+					Region:     "USA, Europe, Brazil",
+					Genre:      "Platform",
+					Year:       "1986",
+					Developer:  "Sega",
+					LastPlayed: time.Unix(1600000000, 0),
 				},
 				{
-					filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Aztec Adventure - The Golden Road to Paradise (World).zip"),
-					"Aztec Adventure (World)",
-					4284567219,
+					Path:     filepath.Clean("/Users/kivutar/testroms/Sega - Master System - Mark III/Aztec Adventure - The Golden Road to Paradise (World).zip"),
+					Name:     "Aztec Adventure (World)",
+					CRC32:    4284567219,
+					CorePath: "/cores/genesis_plus_gx_libretro.so",
+					Region:   "World",
 				},
 			},
 		}
@@ -133,3 +150,93 @@ func TestShortName(t *testing.T) {
 		})
 	}
 }
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	csv := filepath.Join(dir, "a.csv")
+
+	Playlists = map[string]Playlist{
+		csv: {{Path: "/roms/a.zip"}, {Path: "/roms/b.zip"}},
+	}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	removed := Prune(func(path string) bool { return path != "/roms/b.zip" })
+	if removed != 1 {
+		t.Errorf("Prune() = %v, want %v", removed, 1)
+	}
+	want := Playlist{{Path: "/roms/a.zip"}}
+	if !reflect.DeepEqual(Playlists[csv], want) {
+		t.Errorf("Prune() = %v, want %v", Playlists[csv], want)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	Playlists = map[string]Playlist{}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	t.Run("Should keep the playlist sorted", func(t *testing.T) {
+		Add("a.csv", Game{Path: "/roms/b.zip", Name: "B", CRC32: 2})
+		Add("a.csv", Game{Path: "/roms/c.zip", Name: "C", CRC32: 3})
+		Add("a.csv", Game{Path: "/roms/a.zip", Name: "A", CRC32: 1})
+		var got []string
+		for _, game := range Get("a.csv") {
+			got = append(got, game.Name)
+		}
+		want := []string{"A", "B", "C"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Should skip duplicates", func(t *testing.T) {
+		if Add("a.csv", Game{Path: "/roms/a.zip", Name: "A"}) {
+			t.Errorf("Add() = true, want false")
+		}
+		if Add("a.csv", Game{Path: "/roms/other.zip", Name: "A", CRC32: 1}) {
+			t.Errorf("Add() = true, want false")
+		}
+		if got := Count("a.csv"); got != 3 {
+			t.Errorf("Count() = %v, want %v", got, 3)
+		}
+	})
+}
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Playlists = map[string]Playlist{}
+	defer func() { Playlists = map[string]Playlist{} }()
+
+	path := filepath.Join(dir, "a.csv")
+	for i := 0; i < 50; i++ {
+		Add(path, Game{Path: filepath.Join("/roms", strconv.Itoa(i)+".zip"), Name: strconv.Itoa(i)})
+	}
+	want := Get(path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Save(path)
+		}()
+	}
+	wg.Wait()
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Save() left the temporary file behind")
+	}
+	Playlists = map[string]Playlist{}
+	settings.Current.PlaylistsDirectory = dir
+	Load()
+	if got := Get(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
@@ -1,12 +1,16 @@
-// Package savefiles takes care of saving the game SRAM to the filesystem
+// Package savefiles takes care of saving the game SRAM and the other
+// persistent memory regions, like the RTC, to the filesystem
 package savefiles
 
 import (
-	"C"
+	"crypto/sha1"
 	"errors"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"unsafe"
 
@@ -16,58 +20,164 @@ import (
 	"github.com/libretro/ludo/utils"
 )
 
+// region is a memory region of the core persisted in its own file
+type region struct {
+	id     uint32
+	ext    string // extension of the file, added to the name of the game
+	backup bool   // whether to keep backups of the file
+}
+
+// regions lists the memory regions persisted for each game. A game is saved as
+// one raw file per region, named after the game with the extension of the
+// region, like other libretro frontends do. New regions only need an entry
+// here. The SRAM must be the first region.
+var regions = []region{
+	{id: libretro.MemorySaveRAM, ext: ".srm", backup: true},
+	{id: libretro.MemoryRTC, ext: ".rtc"},
+}
+
 var mutex sync.Mutex
 
-// path returns the path of the SRAM file for the current core
-func path() string {
+// checksums are the hashes of the regions as they were last loaded or saved,
+// to avoid writing the same data again
+var checksums = map[uint32][sha1.Size]byte{}
+
+// backedUp is true once the SRAM file has been backed up for the current game
+var backedUp bool
+
+// path returns the path of the file of a memory region for the current game
+func path(r region) string {
 	return filepath.Join(
 		settings.Current.SavefilesDirectory,
-		utils.FileName(state.GamePath)+".srm")
+		utils.FileName(state.GamePath)+r.ext)
 }
 
-// SaveSRAM saves the game SRAM to the filesystem
-func SaveSRAM() error {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	if !state.CoreRunning {
-		return errors.New("core not running")
-	}
+// memory returns a memory region of the core, it is replaced in tests
+var memory = coreMemory
 
-	len := state.Core.GetMemorySize(libretro.MemorySaveRAM)
-	ptr := state.Core.GetMemoryData(libretro.MemorySaveRAM)
+// coreMemory returns a memory region of the core as a go slice pointing to the
+// core memory, or nil if the core doesn't expose this region
+func coreMemory(r region) []byte {
+	len := state.Core.GetMemorySize(r.id)
+	ptr := state.Core.GetMemoryData(r.id)
 	if ptr == nil || len == 0 {
-		return errors.New("unable to get SRAM address")
+		return nil
 	}
 
-	// convert the C array to a go slice
-	bytes := C.GoBytes(ptr, C.int(len))
-	err := os.MkdirAll(settings.Current.SavefilesDirectory, os.ModePerm)
+	// this *[1 << 30]byte points to the same memory as ptr, allowing to
+	// overwrite this memory
+	return (*[1 << 30]byte)(unsafe.Pointer(ptr))[:len:len]
+}
+
+// backupsDirectory returns the directory holding the SRAM backups
+func backupsDirectory() string {
+	return filepath.Join(settings.Current.SavefilesDirectory, "backups")
+}
+
+// writeAtomic writes data to a temporary file and renames it, so the
+// destination is never left half written in case of crash or power loss
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	fd, err := os.Create(tmp)
 	if err != nil {
 		return err
 	}
 
-	fd, err := os.Create(path())
+	_, err = fd.Write(data)
 	if err != nil {
+		fd.Close()
+		os.Remove(tmp)
 		return err
 	}
 
-	_, err = fd.Write(bytes)
+	err = fd.Sync()
 	if err != nil {
 		fd.Close()
+		os.Remove(tmp)
 		return err
 	}
 
 	err = fd.Close()
 	if err != nil {
+		os.Remove(tmp)
 		return err
 	}
 
-	return fd.Sync()
+	return os.Rename(tmp, path)
 }
 
-// LoadSRAM saves the game SRAM to the filesystem
-func LoadSRAM() error {
+// backup copies the SRAM file to the backups directory, and removes the
+// oldest backups of the game to keep settings.Current.SRAMBackups of them
+func backup() error {
+	if settings.Current.SRAMBackups <= 0 {
+		return nil
+	}
+
+	bytes, err := ioutil.ReadFile(path(regions[0]))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(backupsDirectory(), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	name := utils.DatedName(state.GamePath) + ".srm"
+	err = writeAtomic(filepath.Join(backupsDirectory(), name), bytes)
+	if err != nil {
+		return err
+	}
+
+	backups, err := Backups()
+	if err != nil {
+		return err
+	}
+	for _, p := range prune(backups, settings.Current.SRAMBackups) {
+		os.Remove(p)
+	}
+	return nil
+}
+
+// prune returns the backups to delete to keep only the n most recent ones.
+// backups must be sorted from the newest to the oldest.
+func prune(backups []string, n int) []string {
+	if len(backups) <= n {
+		return nil
+	}
+	return backups[n:]
+}
+
+// Backups returns the paths of the SRAM backups of the current game, from the
+// newest to the oldest
+func Backups() ([]string, error) {
+	files, err := ioutil.ReadDir(backupsDirectory())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Game names often contain brackets, they can't be used in a glob pattern
+	prefix := utils.FileName(state.GamePath) + "@"
+	var paths []string
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), prefix) && strings.HasSuffix(f.Name(), ".srm") {
+			paths = append(paths, filepath.Join(backupsDirectory(), f.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// SaveSRAM saves the game SRAM and the other persistent memory regions to
+// the filesystem. Nothing is written for a region that didn't change since it
+// was loaded or last saved. The first time the SRAM changes, the previous SRAM
+// file is backed up.
+func SaveSRAM() error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -75,20 +185,122 @@ func LoadSRAM() error {
 		return errors.New("core not running")
 	}
 
-	len := state.Core.GetMemorySize(libretro.MemorySaveRAM)
-	ptr := state.Core.GetMemoryData(libretro.MemorySaveRAM)
-	if ptr == nil || len == 0 {
+	found := false
+	for _, r := range regions {
+		mem := memory(r)
+		if mem == nil {
+			continue
+		}
+		found = true
+
+		// copy the core memory, it keeps changing while the game runs
+		bytes := append([]byte{}, mem...)
+		sum := sha1.Sum(bytes)
+		if sum == checksums[r.id] {
+			continue
+		}
+
+		err := os.MkdirAll(settings.Current.SavefilesDirectory, os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		if r.backup && !backedUp {
+			if err := backup(); err != nil {
+				log.Println("[Savefiles]: Backup failed:", err)
+			}
+			backedUp = true
+		}
+
+		err = writeAtomic(path(r), bytes)
+		if err != nil {
+			return err
+		}
+
+		checksums[r.id] = sum
+	}
+
+	if !found {
 		return errors.New("unable to get SRAM address")
 	}
+	return nil
+}
 
-	// this *[1 << 30]byte points to the same memory as ptr, allowing to
-	// overwrite this memory
-	destination := (*[1 << 30]byte)(unsafe.Pointer(ptr))[:len:len]
-	source, err := ioutil.ReadFile(path())
+// load copies the content of a file to a memory region of the game
+func load(r region, source string) error {
+	if !state.CoreRunning {
+		return errors.New("core not running")
+	}
+
+	destination := memory(r)
+	if destination == nil {
+		return errors.New("unable to get memory address")
+	}
+
+	bytes, err := ioutil.ReadFile(source)
 	if err != nil {
 		return err
 	}
-	copy(destination, source)
+	copy(destination, bytes)
+	checksums[r.id] = sha1.Sum(destination)
+
+	return nil
+}
+
+// LoadSRAM loads the game SRAM and the other persistent memory regions from
+// the filesystem
+func LoadSRAM() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	checksums = map[uint32][sha1.Size]byte{}
+	backedUp = false
+
+	if !state.CoreRunning {
+		return errors.New("core not running")
+	}
 
+	var errs []error
+	for _, r := range regions {
+		if memory(r) == nil {
+			continue
+		}
+		if err := load(r, path(r)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
 	return nil
 }
+
+// RestoreBackup loads a SRAM backup in the game SRAM and saves it as the
+// current SRAM file. The current SRAM file is backed up first.
+func RestoreBackup(backupPath string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !state.CoreRunning {
+		return errors.New("core not running")
+	}
+
+	bytes, err := ioutil.ReadFile(backupPath)
+	if err != nil {
+		return err
+	}
+
+	if err := backup(); err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(settings.Current.SavefilesDirectory, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(path(regions[0]), bytes); err != nil {
+		return err
+	}
+
+	return load(regions[0], path(regions[0]))
+}
@@ -0,0 +1,168 @@
+package savefiles
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+func Test_writeAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "game.srm")
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("writeAtomic() wrote %q, want %q", got, "new")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("writeAtomic() left the temporary file behind")
+	}
+}
+
+func Test_prune(t *testing.T) {
+	backups := []string{"c@3.srm", "c@2.srm", "c@1.srm"}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{5, nil},
+		{3, nil},
+		{2, []string{"c@1.srm"}},
+		{0, backups},
+	}
+	for _, tt := range tests {
+		if got := prune(backups, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prune(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func Test_SaveSRAM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings.Current.SavefilesDirectory = dir
+	settings.Current.SRAMBackups = 0
+	state.GamePath = "/roms/Pokemon - Gold Version (USA, Europe).gbc"
+	state.CoreRunning = true
+	defer func() { state.CoreRunning = false }()
+
+	mem := map[uint32][]byte{
+		libretro.MemorySaveRAM: []byte("sram"),
+		libretro.MemoryRTC:     []byte("rtc"),
+	}
+	memory = func(r region) []byte { return mem[r.id] }
+	defer func() { memory = coreMemory }()
+
+	srm := filepath.Join(dir, "Pokemon - Gold Version (USA, Europe).srm")
+	rtc := filepath.Join(dir, "Pokemon - Gold Version (USA, Europe).rtc")
+
+	t.Run("Saves every region in its own file", func(t *testing.T) {
+		if err := SaveSRAM(); err != nil {
+			t.Fatal(err)
+		}
+		for path, want := range map[string]string{srm: "sram", rtc: "rtc"} {
+			if got, _ := ioutil.ReadFile(path); string(got) != want {
+				t.Errorf("%s = %q, want %q", path, got, want)
+			}
+		}
+	})
+
+	t.Run("Skips the regions that didn't change", func(t *testing.T) {
+		os.Remove(srm)
+		copy(mem[libretro.MemoryRTC], "RTC")
+		if err := SaveSRAM(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(srm); !os.IsNotExist(err) {
+			t.Errorf("SaveSRAM() wrote the unchanged SRAM again")
+		}
+		if got, _ := ioutil.ReadFile(rtc); string(got) != "RTC" {
+			t.Errorf("%s = %q, want %q", rtc, got, "RTC")
+		}
+	})
+
+	t.Run("Loads every region back", func(t *testing.T) {
+		ioutil.WriteFile(srm, []byte("SRAM"), 0644)
+		mem[libretro.MemorySaveRAM] = make([]byte, 4)
+		mem[libretro.MemoryRTC] = make([]byte, 3)
+		if err := LoadSRAM(); err != nil {
+			t.Fatal(err)
+		}
+		if string(mem[libretro.MemorySaveRAM]) != "SRAM" || string(mem[libretro.MemoryRTC]) != "RTC" {
+			t.Errorf("LoadSRAM() loaded %q", mem)
+		}
+	})
+
+	t.Run("Ignores the regions the core doesn't expose", func(t *testing.T) {
+		os.Remove(rtc)
+		delete(mem, libretro.MemoryRTC)
+		if err := LoadSRAM(); err != nil {
+			t.Fatal(err)
+		}
+		mem[libretro.MemorySaveRAM][0] = 's'
+		if err := SaveSRAM(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(rtc); !os.IsNotExist(err) {
+			t.Errorf("SaveSRAM() wrote a missing region")
+		}
+	})
+}
+
+func Test_Backups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings.Current.SavefilesDirectory = dir
+	state.GamePath = "/roms/Game [T-En by Someone] (USA) [!].sfc"
+
+	if got, err := Backups(); err != nil || len(got) != 0 {
+		t.Fatalf("Backups() = %v, %v, want none", got, err)
+	}
+
+	os.MkdirAll(backupsDirectory(), os.ModePerm)
+	for _, name := range []string{
+		"Game [T-En by Someone] (USA) [!]@2020-01-01-10-00-00.srm",
+		"Game [T-En by Someone] (USA) [!]@2020-01-02-10-00-00.srm",
+		"Game [T-En by Someone] (USA) [!]@2020-01-02-10-00-00.srm.tmp",
+		"Game [T-En by Someone] (USA) [!] (Rev 1)@2020-01-03-10-00-00.srm",
+		"Game (USA)@2020-01-03-10-00-00.srm",
+	} {
+		ioutil.WriteFile(filepath.Join(backupsDirectory(), name), nil, 0644)
+	}
+
+	want := []string{
+		filepath.Join(backupsDirectory(), "Game [T-En by Someone] (USA) [!]@2020-01-02-10-00-00.srm"),
+		filepath.Join(backupsDirectory(), "Game [T-En by Someone] (USA) [!]@2020-01-01-10-00-00.srm"),
+	}
+	if got, err := Backups(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Backups() = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,38 @@
+package savestates
+
+import (
+	"bytes"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// magic starts the compressed savestates. Raw savestates are written as is
+// by the cores, so they don't have a header.
+var magic = []byte("LUDOZST\x00")
+
+// compress wraps a raw savestate in a zstd container
+func compress(raw []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(raw, append([]byte{}, magic...)), nil
+}
+
+// decompress returns the raw savestate from a zstd container. Data without
+// the magic header is returned unchanged, so savestates written before
+// compression was supported can still be loaded. size is the savestate size
+// reported by the core, the decoded data is limited to twice that size plus a
+// margin, so a corrupted file can't exhaust the memory.
+func decompress(data []byte, size uint) ([]byte, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return data, nil
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(size)*2+1<<20))
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data[len(magic):], nil)
+}
@@ -0,0 +1,51 @@
+package savestates
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_compress(t *testing.T) {
+	raw := bytes.Repeat([]byte("ludo savestate "), 1000)
+
+	data, err := compress(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, magic) {
+		t.Errorf("compress() output has no magic header")
+	}
+	if len(data) >= len(raw) {
+		t.Errorf("compress() = %d bytes, want less than %d", len(data), len(raw))
+	}
+
+	got, err := decompress(data, uint(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("decompress() didn't restore the original savestate")
+	}
+}
+
+func Test_decompress_raw(t *testing.T) {
+	raw := []byte{0x01, 0x02, 0x03, 0x04}
+	got, err := decompress(raw, uint(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("decompress() = %v, want %v", got, raw)
+	}
+}
+
+func Test_decompress_limit(t *testing.T) {
+	raw := make([]byte, 4<<20)
+	data, err := compress(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decompress(data, 1024); err == nil {
+		t.Errorf("decompress() should fail past the size of the savestate")
+	}
+}
@@ -3,16 +3,72 @@
 package savestates
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	ntf "github.com/libretro/ludo/notifications"
 	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 )
 
+// Metadata describes the conditions in which a savestate was created. It is
+// stored next to the savestate, in a JSON file of the same name.
+type Metadata struct {
+	CoreName    string    `json:"core_name"`
+	CoreVersion string    `json:"core_version"`
+	ContentCRC  uint32    `json:"content_crc"`
+	Size        uint      `json:"size"`
+	Timestamp   time.Time `json:"timestamp"`
+	Screenshot  string    `json:"screenshot,omitempty"`
+}
+
+// MetadataPath returns the path of the metadata file of a savestate
+func MetadataPath(path string) string {
+	return strings.TrimSuffix(path, ".state") + ".json"
+}
+
+// ReadMetadata reads the metadata of a savestate. Savestates created by older
+// versions of Ludo have no metadata, in that case the error satisfies
+// os.IsNotExist.
+func ReadMetadata(path string) (Metadata, error) {
+	var m Metadata
+	bytes, err := ioutil.ReadFile(MetadataPath(path))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(bytes, &m)
+	return m, err
+}
+
+// currentMetadata returns the metadata of a savestate of the running game
+func currentMetadata(size uint) Metadata {
+	si := state.Core.GetSystemInfo()
+	return Metadata{
+		CoreName:    si.LibraryName,
+		CoreVersion: si.LibraryVersion,
+		ContentCRC:  state.GameCRC,
+		Size:        size,
+		Timestamp:   time.Now(),
+	}
+}
+
+// writeMetadata writes the metadata of a savestate next to it
+func writeMetadata(path string, m Metadata) error {
+	bytes, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(MetadataPath(path), bytes, 0644)
+}
+
 // Save the current state to the filesystem. name is the name of the
-// savestate file to save to, without extension.
+// savestate file to save to, without extension. The savestate is compressed
+// if enabled in the settings.
 func Save(name string) error {
 	s := state.Core.SerializeSize()
 	bytes, err := state.Core.Serialize(s)
@@ -24,16 +80,87 @@ func Save(name string) error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(path, bytes, 0644)
+	if settings.Current.SavestatesCompression {
+		bytes, err = compress(bytes)
+		if err != nil {
+			return err
+		}
+	}
+	err = ioutil.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return err
+	}
+
+	m := currentMetadata(s)
+	screenshot := filepath.Join(settings.Current.ScreenshotsDirectory, name+".png")
+	if _, err := os.Stat(screenshot); err == nil {
+		m.Screenshot = screenshot
+	}
+	return writeMetadata(path, m)
 }
 
-// Load the state from the filesystem
+// check compares the metadata of a savestate with the metadata of the running
+// core and game. It returns an error if the savestate can't be loaded, and
+// warnings if it can be loaded but may not work as expected.
+func check(m, current Metadata) (warnings []string, err error) {
+	if m.CoreName != "" && m.CoreName != current.CoreName {
+		return nil, fmt.Errorf("savestate was created with %s, not %s", m.CoreName, current.CoreName)
+	}
+	if m.Size != 0 && m.Size != current.Size {
+		warnings = append(warnings, fmt.Sprintf("savestate size is %d bytes, the core expects %d bytes",
+			m.Size, current.Size))
+	}
+	if m.CoreVersion != current.CoreVersion {
+		warnings = append(warnings, fmt.Sprintf("savestate was created with %s %s, running %s",
+			m.CoreName, m.CoreVersion, current.CoreVersion))
+	}
+	if m.ContentCRC != 0 && current.ContentCRC != 0 && m.ContentCRC != current.ContentCRC {
+		warnings = append(warnings, fmt.Sprintf("savestate was created with a different content (CRC %08X, running %08X)",
+			m.ContentCRC, current.ContentCRC))
+	}
+	return warnings, nil
+}
+
+// Load the state from the filesystem. If the savestate has metadata, it is
+// checked against the running core and game first. Compressed and raw
+// savestates are both supported.
 func Load(path string) error {
 	s := state.Core.SerializeSize()
+
+	m, err := ReadMetadata(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		warnings, err := check(m, currentMetadata(s))
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			ntf.DisplayAndLog(ntf.Warning, "Savestates", "%s", w)
+		}
+	}
+
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
+	bytes, err = decompress(bytes, s)
+	if err != nil {
+		return err
+	}
 	err = state.Core.Unserialize(bytes, s)
 	return err
 }
+
+// Delete removes a savestate and its metadata
+func Delete(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	err := os.Remove(MetadataPath(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package savestates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Metadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Super Metroid (USA)@slot0.state")
+	if got := MetadataPath(path); got != filepath.Join(dir, "Super Metroid (USA)@slot0.json") {
+		t.Errorf("MetadataPath() = %v", got)
+	}
+
+	if _, err := ReadMetadata(path); !os.IsNotExist(err) {
+		t.Errorf("ReadMetadata() = %v, want a not exist error", err)
+	}
+
+	want := Metadata{
+		CoreName:    "Snes9x",
+		CoreVersion: "1.60",
+		ContentCRC:  0xd63ed5f8,
+		Size:        1024,
+		Timestamp:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Screenshot:  "/screenshots/Super Metroid (USA)@slot0.png",
+	}
+	if err := writeMetadata(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMetadata() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_check(t *testing.T) {
+	current := Metadata{CoreName: "Snes9x", CoreVersion: "1.60", ContentCRC: 1, Size: 1024}
+	tests := []struct {
+		name     string
+		m        Metadata
+		warnings int
+		err      bool
+	}{
+		{"same", current, 0, false},
+		{"other core", Metadata{CoreName: "bsnes", CoreVersion: "1.60", ContentCRC: 1, Size: 1024}, 0, true},
+		{"other version", Metadata{CoreName: "Snes9x", CoreVersion: "1.59", ContentCRC: 1, Size: 1024}, 1, false},
+		{"other content", Metadata{CoreName: "Snes9x", CoreVersion: "1.60", ContentCRC: 2, Size: 1024}, 1, false},
+		{"other size", Metadata{CoreName: "Snes9x", CoreVersion: "1.60", ContentCRC: 1, Size: 2048}, 1, false},
+		{"unknown content", Metadata{CoreName: "Snes9x", CoreVersion: "1.60", Size: 1024}, 0, false},
+		{"everything differs", Metadata{CoreName: "Snes9x", CoreVersion: "1.59", ContentCRC: 2, Size: 2048}, 3, false},
+	}
+	for _, tt := range tests {
+		warnings, err := check(tt.m, current)
+		if len(warnings) != tt.warnings || (err != nil) != tt.err {
+			t.Errorf("check(%s) = %v, %v", tt.name, warnings, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package savestates
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// AutoSlot is the slot of the savestate created when a game is closed
+const AutoSlot = -1
+
+// MaxSlot is the highest numbered slot
+const MaxSlot = 9
+
+// Slot is the slot used by the save and load hotkeys
+var Slot = 0
+
+// SlotName returns the name of the savestate of a game in a given slot,
+// without extension
+func SlotName(gamePath string, slot int) string {
+	if slot == AutoSlot {
+		return utils.FileName(gamePath) + "@auto"
+	}
+	return fmt.Sprintf("%s@slot%d", utils.FileName(gamePath), slot)
+}
+
+// SlotPath returns the path of the savestate of a game in a given slot
+func SlotPath(gamePath string, slot int) string {
+	return filepath.Join(settings.Current.SavestatesDirectory, SlotName(gamePath, slot)+".state")
+}
+
+// SlotLabel returns a human readable name for a slot
+func SlotLabel(slot int) string {
+	if slot == AutoSlot {
+		return "Auto"
+	}
+	return fmt.Sprintf("Slot %d", slot)
+}
+
+// NextSlot selects the next slot, going back to the auto slot after MaxSlot
+func NextSlot() {
+	Slot++
+	if Slot > MaxSlot {
+		Slot = AutoSlot
+	}
+}
+
+// PrevSlot selects the previous slot, going to MaxSlot after the auto slot
+func PrevSlot() {
+	Slot--
+	if Slot < AutoSlot {
+		Slot = MaxSlot
+	}
+}
+
+// SaveSlot saves the state of the current game in the current slot
+func SaveSlot() error {
+	return Save(SlotName(state.GamePath, Slot))
+}
+
+// LoadSlot loads the state of the current game from the current slot
+func LoadSlot() error {
+	return Load(SlotPath(state.GamePath, Slot))
+}
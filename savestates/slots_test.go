@@ -0,0 +1,36 @@
+package savestates
+
+import "testing"
+
+func Test_SlotName(t *testing.T) {
+	tests := []struct {
+		slot int
+		want string
+	}{
+		{AutoSlot, "Super Metroid (USA)@auto"},
+		{0, "Super Metroid (USA)@slot0"},
+		{9, "Super Metroid (USA)@slot9"},
+	}
+	for _, tt := range tests {
+		if got := SlotName("/roms/Super Metroid (USA).sfc", tt.slot); got != tt.want {
+			t.Errorf("SlotName() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func Test_NextSlot_PrevSlot(t *testing.T) {
+	Slot = MaxSlot
+	NextSlot()
+	if Slot != AutoSlot {
+		t.Errorf("NextSlot() = %d, want %d", Slot, AutoSlot)
+	}
+	NextSlot()
+	if Slot != 0 {
+		t.Errorf("NextSlot() = %d, want %d", Slot, 0)
+	}
+	PrevSlot()
+	PrevSlot()
+	if Slot != MaxSlot {
+		t.Errorf("PrevSlot() = %d, want %d", Slot, MaxSlot)
+	}
+}
@@ -0,0 +1,117 @@
+package savesync
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a minimal WebDAV and S3 stand-in storing files in memory
+type fakeServer struct {
+	sync.Mutex
+	files  map[string][]byte
+	dirs   map[string]bool
+	webdav bool
+	t      *testing.T
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	p := r.URL.Path
+	if !s.webdav {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/20200102/eu-west-1/s3/aws4_request") {
+			s.t.Errorf("bad Authorization header %q", auth)
+		}
+	} else if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.files[p]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		if !s.webdav && r.Header.Get("x-amz-content-sha256") != sha256Hex(data) {
+			s.t.Errorf("bad payload hash")
+		}
+		if s.webdav && !s.dirs[p[:strings.LastIndex(p, "/")]] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.files[p] = data
+		if s.webdav {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case "MKCOL":
+		s.dirs[strings.TrimSuffix(p, "/")] = true
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func testBackend(t *testing.T, b Backend) {
+	if _, err := b.Get("manifest.json"); err != ErrNotFound {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := b.Put("savefiles/Tetris (World).srm", []byte("save")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := b.Get("savefiles/Tetris (World).srm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "save" {
+		t.Errorf("Get() = %q, want %q", data, "save")
+	}
+}
+
+func Test_WebDAV(t *testing.T) {
+	fake := &fakeServer{files: map[string][]byte{}, dirs: map[string]bool{"/dav": true}, webdav: true, t: t}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	testBackend(t, &WebDAV{URL: server.URL + "/dav/", Username: "user", Password: "pass"})
+
+	if !fake.dirs["/dav/savefiles"] {
+		t.Errorf("Put() didn't create the parent collection")
+	}
+}
+
+func Test_S3(t *testing.T) {
+	fake := &fakeServer{files: map[string][]byte{}, t: t}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	testBackend(t, &S3{
+		Endpoint:  server.URL,
+		Bucket:    "saves",
+		Region:    "eu-west-1",
+		AccessKey: "key",
+		SecretKey: "secret",
+		now:       func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) },
+	})
+
+	if _, ok := fake.files["/saves/savefiles/Tetris (World).srm"]; !ok {
+		t.Errorf("Put() didn't store the object in the bucket")
+	}
+}
+
+func Test_escapePath(t *testing.T) {
+	got := escapePath("savestates/Tetris (World)@slot1.state")
+	want := "savestates/Tetris%20%28World%29%40slot1.state"
+	if got != want {
+		t.Errorf("escapePath() = %v, want %v", got, want)
+	}
+}
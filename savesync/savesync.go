@@ -0,0 +1,414 @@
+// Package savesync synchronizes the savefiles and savestates with a remote
+// server, so games can be continued on another device. The remote can be a
+// WebDAV server or an S3 compatible object storage.
+//
+// The remote keeps a manifest listing the hash and modification time of each
+// file. A local copy of the manifest, as it was after the last sync, allows
+// to know which side changed a file. When both sides changed the same file,
+// a conflict is reported and the user has to choose which version to keep.
+// Deleted files are not synchronized.
+package savesync
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+)
+
+// ErrNotFound is returned by backends when a file doesn't exist on the remote
+var ErrNotFound = errors.New("file not found on the server")
+
+// Backend stores files on a remote server
+type Backend interface {
+	Get(name string) ([]byte, error)
+	Put(name string, data []byte) error
+}
+
+// File describes a version of a synchronized file
+type File struct {
+	Hash    string    `json:"hash"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// Manifest lists the synchronized files, by name relative to the sync root,
+// like savefiles/Tetris.srm
+type Manifest map[string]File
+
+// Conflict is a file modified both locally and on the remote since the last
+// sync
+type Conflict struct {
+	Name   string
+	Local  File
+	Remote File
+}
+
+// manifestName is the name of the manifest on the remote
+const manifestName = "manifest.json"
+
+// mutex serializes the syncs, which can run in the background
+var mutex sync.Mutex
+
+// conflicts are waiting to be resolved by the user
+var conflicts []Conflict
+
+// ErrTimeout is returned when a sync doesn't finish in time
+var ErrTimeout = errors.New("save sync timed out")
+
+// direction restricts a sync to the downloads or the uploads
+type direction int
+
+const (
+	both direction = iota
+	pull
+	push
+)
+
+// backend returns the backend chosen in the settings, or nil if sync is
+// disabled. client is used for the requests if not nil.
+func backend(client *http.Client) Backend {
+	s := settings.Current
+	switch s.SyncBackend {
+	case "WebDAV":
+		return &WebDAV{URL: s.SyncURL, Username: s.SyncUsername, Password: s.SyncPassword, Client: client}
+	case "S3":
+		return &S3{
+			Endpoint:  s.SyncURL,
+			Bucket:    s.SyncBucket,
+			Region:    s.SyncRegion,
+			AccessKey: s.SyncUsername,
+			SecretKey: s.SyncPassword,
+			Client:    client,
+		}
+	}
+	return nil
+}
+
+// basePath returns the path of the manifest of the last sync
+func basePath() string {
+	return filepath.Join(xdg.DataHome, "ludo", "sync.json")
+}
+
+// directories maps the remote directories to the local ones
+func directories() map[string]string {
+	return map[string]string{
+		"savefiles":  settings.Current.SavefilesDirectory,
+		"savestates": settings.Current.SavestatesDirectory,
+	}
+}
+
+// localPath returns the local path of a synchronized file
+func localPath(name string) (string, error) {
+	parts := strings.SplitN(name, "/", 2)
+	dir, ok := directories()[parts[0]]
+	if len(parts) != 2 || !ok || parts[1] != filepath.Base(parts[1]) {
+		return "", errors.New("invalid file name " + name)
+	}
+	return filepath.Join(dir, parts[1]), nil
+}
+
+func hash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// scan lists the local files accepted by filter
+func scan(filter func(name string) bool) (Manifest, error) {
+	m := Manifest{}
+	for remote, dir := range directories() {
+		files, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range files {
+			name := remote + "/" + fi.Name()
+			if fi.IsDir() || strings.HasSuffix(fi.Name(), ".tmp") || !filter(name) {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+			if err != nil {
+				return nil, err
+			}
+			m[name] = File{Hash: hash(data), ModTime: fi.ModTime().UTC()}
+		}
+	}
+	return m, nil
+}
+
+func readManifest(data []byte) (Manifest, error) {
+	m := Manifest{}
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
+
+func remoteManifest(b Backend) (Manifest, error) {
+	data, err := b.Get(manifestName)
+	if err == ErrNotFound {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readManifest(data)
+}
+
+func putManifest(b Backend, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.Put(manifestName, data)
+}
+
+func loadBase() Manifest {
+	data, err := ioutil.ReadFile(basePath())
+	if err != nil {
+		return Manifest{}
+	}
+	m, err := readManifest(data)
+	if err != nil {
+		return Manifest{}
+	}
+	return m
+}
+
+func saveBase(m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(basePath()), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(basePath(), data, 0644)
+}
+
+func upload(b Backend, name string) error {
+	path, err := localPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return b.Put(name, data)
+}
+
+func download(b Backend, name string, f File) error {
+	path, err := localPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := b.Get(name)
+	if err != nil {
+		return err
+	}
+	if hash(data) != f.Hash {
+		return errors.New("corrupted download " + name)
+	}
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, f.ModTime, f.ModTime)
+}
+
+// syncFiles synchronizes the files accepted by filter. It returns the files that
+// changed on both sides.
+func syncFiles(b Backend, filter func(name string) bool) ([]Conflict, error) {
+	return transfer(b, filter, both, time.Time{})
+}
+
+// transfer synchronizes the files accepted by filter in the given direction,
+// the files changed on the other side are left for a later sync. It gives up
+// with ErrTimeout once the deadline is passed, if any. It returns the files
+// that changed on both sides.
+func transfer(b Backend, filter func(name string) bool, dir direction, deadline time.Time) ([]Conflict, error) {
+	expired := func() bool {
+		return !deadline.IsZero() && time.Now().After(deadline)
+	}
+
+	remote, err := remoteManifest(b)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scan(filter)
+	if err != nil {
+		return nil, err
+	}
+	base := loadBase()
+
+	names := []string{}
+	for name := range local {
+		names = append(names, name)
+	}
+	for name := range remote {
+		if _, ok := local[name]; !ok && filter(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var found []Conflict
+	uploaded := false
+	for _, name := range names {
+		l, lok := local[name]
+		r, rok := remote[name]
+		b0, bok := base[name]
+		switch {
+		case lok && rok && l.Hash == r.Hash:
+			base[name] = r
+		case lok && (!rok || bok && r.Hash == b0.Hash):
+			if dir == pull {
+				continue
+			}
+			if expired() {
+				return found, ErrTimeout
+			}
+			if err := upload(b, name); err != nil {
+				return found, err
+			}
+			remote[name] = l
+			base[name] = l
+			uploaded = true
+		case rok && (!lok || bok && l.Hash == b0.Hash):
+			if dir == push {
+				continue
+			}
+			if expired() {
+				return found, ErrTimeout
+			}
+			if err := download(b, name, r); err != nil {
+				return found, err
+			}
+			base[name] = r
+		default:
+			found = append(found, Conflict{Name: name, Local: l, Remote: r})
+		}
+	}
+
+	if uploaded {
+		if err := putManifest(b, remote); err != nil {
+			return found, err
+		}
+	}
+	return found, saveBase(base)
+}
+
+// resolve keeps the local or the remote version of a conflicting file
+func resolve(b Backend, c Conflict, keepLocal bool) error {
+	remote, err := remoteManifest(b)
+	if err != nil {
+		return err
+	}
+	base := loadBase()
+
+	if keepLocal {
+		if err := upload(b, c.Name); err != nil {
+			return err
+		}
+		remote[c.Name] = c.Local
+		if err := putManifest(b, remote); err != nil {
+			return err
+		}
+		base[c.Name] = c.Local
+	} else {
+		if err := download(b, c.Name, remote[c.Name]); err != nil {
+			return err
+		}
+		base[c.Name] = remote[c.Name]
+	}
+	return saveBase(base)
+}
+
+// gameFilter accepts the files belonging to a game, like its SRAM and its
+// savestates
+func gameFilter(gamePath string) func(name string) bool {
+	game := utils.FileName(gamePath)
+	return func(name string) bool {
+		base := name[strings.Index(name, "/")+1:]
+		return strings.HasPrefix(base, game+".") || strings.HasPrefix(base, game+"@")
+	}
+}
+
+// Enabled tells if a sync backend is chosen in the settings
+func Enabled() bool {
+	return backend(nil) != nil
+}
+
+// PullGame downloads the savefiles and savestates of a game that changed on
+// the remote, it is meant to be called before loading the SRAM. It gives up
+// with ErrTimeout after about timeout, the files already downloaded are kept.
+// Conflicts are kept until they are fetched with Conflicts.
+func PullGame(gamePath string, timeout time.Duration) error {
+	b := backend(&http.Client{Timeout: timeout})
+	if b == nil || gamePath == "" {
+		return nil
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	found, err := transfer(b, gameFilter(gamePath), pull, time.Now().Add(timeout))
+	conflicts = append(conflicts, found...)
+	return err
+}
+
+// PushGame uploads the savefiles and savestates of a game that changed
+// locally. It is safe to call from another goroutine. Conflicts are kept until
+// they are fetched with Conflicts.
+func PushGame(gamePath string) error {
+	b := backend(nil)
+	if b == nil || gamePath == "" {
+		return nil
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	found, err := transfer(b, gameFilter(gamePath), push, time.Time{})
+	conflicts = append(conflicts, found...)
+	return err
+}
+
+// Conflicts returns the unresolved conflicts and forgets them
+func Conflicts() []Conflict {
+	mutex.Lock()
+	defer mutex.Unlock()
+	c := conflicts
+	conflicts = nil
+	return c
+}
+
+// Resolve keeps the local or the remote version of a conflicting file
+func Resolve(c Conflict, keepLocal bool) error {
+	b := backend(nil)
+	if b == nil {
+		return errors.New("save sync is disabled")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	return resolve(b, c, keepLocal)
+}
@@ -0,0 +1,188 @@
+package savesync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/settings"
+)
+
+// memory is a backend keeping the files in memory
+type memory map[string][]byte
+
+func (m memory) Get(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (m memory) Put(name string, data []byte) error {
+	m[name] = append([]byte{}, data...)
+	return nil
+}
+
+// device simulates a device with its own directories and sync state
+func device(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ludo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Current.SavefilesDirectory = filepath.Join(dir, "savefiles")
+	settings.Current.SavestatesDirectory = filepath.Join(dir, "savestates")
+	xdg.DataHome = filepath.Join(dir, "data")
+	os.MkdirAll(settings.Current.SavefilesDirectory, os.ModePerm)
+	os.MkdirAll(settings.Current.SavestatesDirectory, os.ModePerm)
+	return func() { os.RemoveAll(dir) }
+}
+
+func write(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func Test_sync(t *testing.T) {
+	remote := memory{}
+	filter := gameFilter("/roms/Tetris.gb")
+
+	// The first device uploads its save
+	cleanA := device(t)
+	defer cleanA()
+	dirA := settings.Current
+	dataA := xdg.DataHome
+	write(t, filepath.Join(dirA.SavefilesDirectory, "Tetris.srm"), "v1")
+	write(t, filepath.Join(dirA.SavefilesDirectory, "Zelda.srm"), "zelda")
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+	if string(remote["savefiles/Tetris.srm"]) != "v1" {
+		t.Errorf("syncFiles() didn't upload the save")
+	}
+	if _, ok := remote["savefiles/Zelda.srm"]; ok {
+		t.Errorf("syncFiles() uploaded the save of another game")
+	}
+
+	// The second device downloads it, plays and uploads a new version
+	cleanB := device(t)
+	defer cleanB()
+	dirB := settings.Current
+	dataB := xdg.DataHome
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+	if got := read(t, filepath.Join(dirB.SavefilesDirectory, "Tetris.srm")); got != "v1" {
+		t.Errorf("syncFiles() downloaded %q, want %q", got, "v1")
+	}
+	write(t, filepath.Join(dirB.SavefilesDirectory, "Tetris.srm"), "v2")
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+
+	// The first device gets the new version
+	settings.Current, xdg.DataHome = dirA, dataA
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+	if got := read(t, filepath.Join(dirA.SavefilesDirectory, "Tetris.srm")); got != "v2" {
+		t.Errorf("syncFiles() downloaded %q, want %q", got, "v2")
+	}
+
+	// Both devices change the save, the second one to sync gets a conflict
+	write(t, filepath.Join(dirA.SavefilesDirectory, "Tetris.srm"), "v3a")
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+	settings.Current, xdg.DataHome = dirB, dataB
+	write(t, filepath.Join(dirB.SavefilesDirectory, "Tetris.srm"), "v3b")
+	c, err := syncFiles(remote, filter)
+	if err != nil || len(c) != 1 || c[0].Name != "savefiles/Tetris.srm" {
+		t.Fatalf("syncFiles() = %v, %v, want a conflict", c, err)
+	}
+
+	// Keeping the version of the server
+	if err := resolve(remote, c[0], false); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, filepath.Join(dirB.SavefilesDirectory, "Tetris.srm")); got != "v3a" {
+		t.Errorf("resolve() = %q, want %q", got, "v3a")
+	}
+	if c, err := syncFiles(remote, filter); err != nil || len(c) != 0 {
+		t.Fatalf("syncFiles() = %v, %v", c, err)
+	}
+}
+
+func Test_transfer(t *testing.T) {
+	remote := memory{}
+	filter := gameFilter("/roms/Tetris.gb")
+
+	clean := device(t)
+	defer clean()
+	dir := settings.Current.SavefilesDirectory
+	write(t, filepath.Join(dir, "Tetris.srm"), "v1")
+
+	if c, err := transfer(remote, filter, pull, time.Time{}); err != nil || len(c) != 0 {
+		t.Fatalf("transfer() = %v, %v", c, err)
+	}
+	if _, ok := remote["savefiles/Tetris.srm"]; ok {
+		t.Errorf("transfer() uploaded while pulling")
+	}
+	if c, err := transfer(remote, filter, push, time.Time{}); err != nil || len(c) != 0 {
+		t.Fatalf("transfer() = %v, %v", c, err)
+	}
+	if string(remote["savefiles/Tetris.srm"]) != "v1" {
+		t.Errorf("transfer() didn't upload the save")
+	}
+
+	cleanB := device(t)
+	defer cleanB()
+	dirB := settings.Current.SavefilesDirectory
+	if c, err := transfer(remote, filter, push, time.Time{}); err != nil || len(c) != 0 {
+		t.Fatalf("transfer() = %v, %v", c, err)
+	}
+	if _, err := os.Stat(filepath.Join(dirB, "Tetris.srm")); !os.IsNotExist(err) {
+		t.Errorf("transfer() downloaded while pushing")
+	}
+	if _, err := transfer(remote, filter, pull, time.Now().Add(-time.Second)); err != ErrTimeout {
+		t.Errorf("transfer() = %v, want %v", err, ErrTimeout)
+	}
+	if c, err := transfer(remote, filter, pull, time.Now().Add(time.Minute)); err != nil || len(c) != 0 {
+		t.Fatalf("transfer() = %v, %v", c, err)
+	}
+	if got := read(t, filepath.Join(dirB, "Tetris.srm")); got != "v1" {
+		t.Errorf("transfer() downloaded %q, want %q", got, "v1")
+	}
+}
+
+func Test_localPath(t *testing.T) {
+	settings.Current.SavefilesDirectory = "/saves"
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"savefiles/Tetris.srm", "/saves/Tetris.srm", false},
+		{"savefiles/../Tetris.srm", "", true},
+		{"other/Tetris.srm", "", true},
+		{"Tetris.srm", "", true},
+	}
+	for _, tt := range tests {
+		got, err := localPath(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("localPath(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package savesync
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// WebDAV stores the files on a WebDAV server, under URL
+type WebDAV struct {
+	URL      string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+func (w *WebDAV) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (w *WebDAV) do(method, name string, body []byte) (*http.Response, error) {
+	url := strings.TrimSuffix(w.URL, "/") + "/" + escapePath(name)
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	return w.client().Do(req)
+}
+
+// Get downloads a file
+func (w *WebDAV) Get(name string) ([]byte, error) {
+	resp, err := w.do(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webdav: GET %s: %s", name, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Put uploads a file, creating its parent collection if needed
+func (w *WebDAV) Put(name string, data []byte) error {
+	resp, err := w.do(http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	// The parent collection doesn't exist yet
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound {
+		if err := w.mkcol(path.Dir(name)); err != nil {
+			return err
+		}
+		resp, err = w.do(http.MethodPut, name, data)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	return fmt.Errorf("webdav: PUT %s: %s", name, resp.Status)
+}
+
+func (w *WebDAV) mkcol(dir string) error {
+	resp, err := w.do("MKCOL", dir+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusMethodNotAllowed: // 405 if it exists
+		return nil
+	}
+	return fmt.Errorf("webdav: MKCOL %s: %s", dir, resp.Status)
+}
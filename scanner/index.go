@@ -0,0 +1,187 @@
+package scanner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/dat"
+)
+
+// IndexEntry is what the scanner learnt about a file. A file is only scanned
+// again if its size or modification time changed. Its games are matched again
+// if the databases changed.
+type IndexEntry struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	CRC     uint32      `json:"crc,omitempty"`
+	MD5     string      `json:"md5,omitempty"`
+	SHA1    string      `json:"sha1,omitempty"`
+	Serial  string      `json:"serial,omitempty"`
+	Games   []IndexGame `json:"games,omitempty"` // empty if nothing matched
+	DB      string      `json:"db,omitempty"`    // fingerprint of the databases Games come from
+}
+
+// IndexGame is a game matched by a file
+type IndexGame struct {
+	System      string `json:"system"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CRC         uint32 `json:"crc"`
+	Genre       string `json:"genre,omitempty"`
+	Year        string `json:"year,omitempty"`
+	Developer   string `json:"developer,omitempty"`
+}
+
+// Index maps the paths of the scanned files to what is known about them
+type Index map[string]IndexEntry
+
+// shared is the scan index of the running scans. Concurrent scans share it so
+// they don't overwrite each other's entries when saving. It is loaded by the
+// first scan and forgotten when the last one finishes.
+var shared struct {
+	sync.Mutex // guards idx, and the entries of idx
+	idx        Index
+	scans      int
+}
+
+// acquireIndex returns the shared index, loading it if no scan is running.
+// Accesses to the index must hold shared.Mutex.
+func acquireIndex() Index {
+	shared.Lock()
+	defer shared.Unlock()
+	if shared.scans == 0 {
+		shared.idx = LoadIndex()
+	}
+	shared.scans++
+	return shared.idx
+}
+
+// releaseIndex saves the shared index once a scan is over, after forgetting
+// the files of dir missing from paths if prune is set
+func releaseIndex(dir string, paths []string, prune bool) error {
+	shared.Lock()
+	defer shared.Unlock()
+	if prune {
+		shared.idx.Prune(dir, paths)
+	}
+	err := shared.idx.Save()
+	shared.scans--
+	if shared.scans == 0 {
+		shared.idx = nil
+	}
+	return err
+}
+
+// indexPath returns the path of the scan index
+func indexPath() string {
+	return filepath.Join(xdg.DataHome, "ludo", "scanindex.json")
+}
+
+// LoadIndex reads the scan index, an empty index is returned if it doesn't
+// exist yet
+func LoadIndex() Index {
+	idx := Index{}
+	data, err := ioutil.ReadFile(indexPath())
+	if err != nil {
+		return idx
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return Index{}
+	}
+	return idx
+}
+
+// Save writes the scan index
+func (idx Index) Save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath()), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := indexPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, indexPath())
+}
+
+// Lookup returns the entry of a file if it didn't change since it was indexed.
+// fresh is false if its games were matched with other databases than db, then
+// only the checksums and serial of the entry are kept.
+func (idx Index) Lookup(path string, fi os.FileInfo, db string) (e IndexEntry, ok, fresh bool) {
+	e, ok = idx[path]
+	if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+		return IndexEntry{}, false, false
+	}
+	if e.DB != db {
+		e.Games = nil
+		return e, true, false
+	}
+	return e, true, true
+}
+
+// Prune forgets the files of dir that are not in paths anymore, it returns
+// the forgotten paths
+func (idx Index) Prune(dir string, paths []string) []string {
+	present := map[string]bool{}
+	for _, path := range paths {
+		present[path] = true
+	}
+	var removed []string
+	for path := range idx {
+		if inDir(dir, path) && !present[path] {
+			delete(idx, path)
+			removed = append(removed, path)
+		}
+	}
+	return removed
+}
+
+// inDir tells if path is located in dir or its subdirectories
+func inDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// indexGames converts matched games to be stored in the index
+func indexGames(games []dat.Game) []IndexGame {
+	var out []IndexGame
+	for _, g := range games {
+		out = append(out, IndexGame{
+			System:      g.System,
+			Name:        g.Name,
+			Description: g.Description,
+			CRC:         uint32(g.ROMs[0].CRC),
+			Genre:       g.Genre,
+			Year:        g.Year,
+			Developer:   g.Developer,
+		})
+	}
+	return out
+}
+
+// games converts the games of the index back, as if they just matched
+func (e IndexEntry) games(path string) []dat.Game {
+	var out []dat.Game
+	for _, g := range e.Games {
+		out = append(out, dat.Game{
+			Name:        g.Name,
+			Description: g.Description,
+			ROMs:        []dat.ROM{{Name: filepath.Base(path), CRC: dat.CRC(g.CRC)}},
+			Path:        path,
+			System:      g.System,
+			Genre:       g.Genre,
+			Year:        g.Year,
+			Developer:   g.Developer,
+		})
+	}
+	return out
+}
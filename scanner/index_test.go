@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/dat"
+	"github.com/libretro/ludo/state"
+)
+
+func Test_inDir(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"/roms", "/roms/snes/game.sfc", true},
+		{"/roms", "/roms/game.sfc", true},
+		{"/roms", "/roms2/game.sfc", false},
+		{"/roms/snes", "/roms/game.sfc", false},
+		{"/roms", "/roms/..game.sfc", true},
+	}
+	for _, tt := range tests {
+		if got := inDir(tt.dir, tt.path); got != tt.want {
+			t.Errorf("inDir(%v, %v) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_Index(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg.DataHome = dir
+
+	roms := filepath.Join(dir, "roms")
+	os.Mkdir(roms, os.ModePerm)
+	rom := filepath.Join(roms, "game.sfc")
+	ioutil.WriteFile(rom, []byte("rom content"), 0644)
+
+	state.DB = dat.NewDB(map[string]dat.Dat{"Nintendo - SNES": {Games: []dat.Game{{
+		Name:        "Game (USA)",
+		Description: "Game (USA)",
+		ROMs:        []dat.ROM{{Name: "Game (USA).sfc", CRC: 0xdad6ee51}},
+	}}}})
+	defer func() { state.DB = dat.DB{} }()
+
+	scan := func() []dat.Game {
+		games := make(chan dat.Game)
+		progress := make(chan Progress, 1)
+		go Scan(roms, []string{rom, filepath.Join(roms, "readme.txt")}, games, progress, nil)
+		go func() {
+			for range progress {
+			}
+		}()
+		var found []dat.Game
+		for game := range games {
+			found = append(found, game)
+		}
+		return found
+	}
+
+	t.Run("Indexes the scanned files", func(t *testing.T) {
+		found := scan()
+		if len(found) != 1 || found[0].System != "Nintendo - SNES" || found[0].Path != rom {
+			t.Fatalf("Scan() = %v", found)
+		}
+		idx := LoadIndex()
+		if len(idx) != 1 || idx[rom].CRC != 0xdad6ee51 || len(idx[rom].Games) != 1 {
+			t.Errorf("LoadIndex() = %+v", idx)
+		}
+	})
+
+	t.Run("Doesn't read unchanged files again", func(t *testing.T) {
+		db := state.DB
+		state.DB = dat.DB{}
+		defer func() { state.DB = db }()
+
+		found := scan()
+		if len(found) != 1 || found[0].Description != "Game (USA)" || found[0].ROMs[0].CRC != 0xdad6ee51 {
+			t.Errorf("Scan() = %v", found)
+		}
+	})
+
+	t.Run("Matches again when the databases changed", func(t *testing.T) {
+		db := state.DB
+		defer func() { state.DB = db }()
+
+		state.DB = dat.NewDB(map[string]dat.Dat{})
+		state.DB.Fingerprint = "empty"
+		if found := scan(); len(found) != 0 {
+			t.Errorf("Scan() = %v", found)
+		}
+		if e := LoadIndex()[rom]; e.CRC != 0xdad6ee51 || len(e.Games) != 0 || e.DB != "empty" {
+			t.Errorf("LoadIndex() = %+v", e)
+		}
+
+		state.DB = dat.NewDB(map[string]dat.Dat{"Nintendo - SNES": {Games: []dat.Game{{
+			Name:        "Game (USA) (Rev 1)",
+			Description: "Game (USA) (Rev 1)",
+			ROMs:        []dat.ROM{{Name: "Game (USA).sfc", CRC: 0xdad6ee51}},
+		}}}})
+		state.DB.Fingerprint = "updated"
+		found := scan()
+		if len(found) != 1 || found[0].Name != "Game (USA) (Rev 1)" {
+			t.Errorf("Scan() = %v", found)
+		}
+		if e := LoadIndex()[rom]; len(e.Games) != 1 || e.DB != "updated" {
+			t.Errorf("LoadIndex() = %+v", e)
+		}
+	})
+
+	t.Run("Scans running at once share the index", func(t *testing.T) {
+		other := filepath.Join(dir, "other", "game.sfc")
+		idx := acquireIndex() // a scan of another directory
+		scan()
+		shared.Lock()
+		idx[other] = IndexEntry{Size: 1}
+		shared.Unlock()
+		if err := releaseIndex(filepath.Dir(other), []string{other}, true); err != nil {
+			t.Fatal(err)
+		}
+		saved := LoadIndex()
+		if _, ok := saved[rom]; !ok {
+			t.Errorf("LoadIndex() = %+v, lost the entries of the first scan", saved)
+		}
+		if _, ok := saved[other]; !ok {
+			t.Errorf("LoadIndex() = %+v, lost the entries of the second scan", saved)
+		}
+		if shared.scans != 0 || shared.idx != nil {
+			t.Errorf("the shared index wasn't released")
+		}
+	})
+
+	t.Run("Scans changed files again", func(t *testing.T) {
+		later := time.Now().Add(time.Hour)
+		os.Chtimes(rom, later, later)
+		ioutil.WriteFile(rom, []byte("other content"), 0644)
+		if found := scan(); len(found) != 0 {
+			t.Errorf("Scan() = %v", found)
+		}
+	})
+
+	t.Run("Prunes missing files", func(t *testing.T) {
+		idx := Index{
+			rom:                      IndexEntry{},
+			filepath.Join(dir, "x"):  IndexEntry{},
+			filepath.Join(roms, "y"): IndexEntry{},
+		}
+		removed := idx.Prune(roms, []string{rom})
+		if len(removed) != 1 || removed[0] != filepath.Join(roms, "y") || len(idx) != 2 {
+			t.Errorf("Prune() = %v, %v", removed, idx)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/libretro/ludo/dat"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+	"github.com/libretro/ludo/utils"
+)
+
+// Progress is the state of a scan
+type Progress struct {
+	Dir      string
+	Done     int           // Number of files scanned
+	Total    int           // Number of files to scan
+	Failed   int           // Number of files that couldn't be read
+	Rate     float64       // Files scanned per second
+	ETA      time.Duration // Estimated time left
+	Finished bool
+	Canceled bool
+}
+
+func newProgress(dir string, done, total, failed int, elapsed time.Duration) Progress {
+	p := Progress{Dir: dir, Done: done, Total: total, Failed: failed}
+	if elapsed > 0 {
+		p.Rate = float64(done) / elapsed.Seconds()
+	}
+	if p.Rate > 0 {
+		p.ETA = time.Duration(float64(total-done) / p.Rate * float64(time.Second))
+	}
+	return p
+}
+
+// String formats the progress to be displayed, like
+// "120/500 files, 45 files/s, 9s left"
+func (p Progress) String() string {
+	s := fmt.Sprintf("%d/%d files", p.Done, p.Total)
+	if p.Rate > 0 && !p.Finished {
+		s += fmt.Sprintf(", %.0f files/s, %s left", p.Rate, p.ETA.Round(time.Second))
+	}
+	return s
+}
+
+// Job is a scan running in the background
+type Job struct {
+	Dir string
+
+	cancel chan struct{}
+	once   sync.Once
+	mutex  sync.Mutex
+	last   Progress
+	dirty  bool // scan requested again while running, guarded by jobs
+}
+
+// Cancel stops the scan, the files already scanned are kept in the playlists
+func (j *Job) Cancel() {
+	j.once.Do(func() { close(j.cancel) })
+}
+
+// Progress returns the last progress reported by the scan
+func (j *Job) Progress() Progress {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.last
+}
+
+var jobs struct {
+	sync.Mutex
+	running []*Job
+}
+
+// Running returns the scans in progress
+func Running() []*Job {
+	jobs.Lock()
+	defer jobs.Unlock()
+	return append([]*Job{}, jobs.running...)
+}
+
+// start registers a new job, or marks the job already scanning dir to scan
+// again once it finishes and returns it
+func start(dir string) (*Job, bool) {
+	jobs.Lock()
+	defer jobs.Unlock()
+	for _, j := range jobs.running {
+		if j.Dir == dir {
+			j.dirty = true
+			return j, false
+		}
+	}
+	j := &Job{Dir: dir, cancel: make(chan struct{}), last: Progress{Dir: dir}}
+	jobs.running = append(jobs.running, j)
+	return j, true
+}
+
+// finish unregisters a job, it tells if a scan was requested again meanwhile
+func finish(j *Job) bool {
+	jobs.Lock()
+	defer jobs.Unlock()
+	for i, r := range jobs.running {
+		if r == j {
+			jobs.running = append(jobs.running[:i], jobs.running[i+1:]...)
+			break
+		}
+	}
+	return j.dirty
+}
+
+// ScanDir scans a full directory in the background, report progress and
+// update the playlists. If the directory is already being scanned, the
+// running job is returned and the directory is scanned again once it
+// finishes, unless it is canceled.
+func ScanDir(dir string, doneCb func()) *Job {
+	j, ok := start(dir)
+	if !ok {
+		return j
+	}
+	n := ntf.DisplayAndLog(ntf.Info, "Menu", "Scanning %s", dir)
+	roms, err := utils.AllFilesIn(dir)
+	if err != nil {
+		n.Update(ntf.Error, err.Error())
+		finish(j)
+		return j
+	}
+	games := make(chan dat.Game)
+	progress := make(chan Progress, 1)
+	go Scan(dir, roms, games, progress, j.cancel)
+
+	// Report the progress until the scan is over
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		for p := range progress {
+			j.mutex.Lock()
+			j.last = p
+			j.mutex.Unlock()
+			if !p.Finished {
+				n.Update(ntf.Info, "Scanning %s", p)
+			}
+		}
+	}()
+
+	// The games found are written by a single goroutine
+	go func() {
+		added := write(games)
+		<-reported
+		p := j.Progress()
+		removed := 0
+		// Forget the games of this directory whose file disappeared
+		if !p.Canceled {
+			removed = playlists.Prune(func(path string) bool {
+				if !inDir(dir, path) {
+					return true
+				}
+				_, err := os.Stat(path)
+				return err == nil
+			})
+		}
+		again := finish(j)
+		doneCb()
+		switch {
+		case p.Canceled:
+			n.Update(ntf.Warning, "Scan canceled. %d new games found.", added)
+		case removed > 0:
+			n.Update(ntf.Success, "Done scanning. %d new games found, %d removed.", added, removed)
+		default:
+			n.Update(ntf.Success, "Done scanning. %d new games found.", added)
+		}
+		// Files changed during the scan
+		if again && !p.Canceled {
+			ScanDir(dir, doneCb)
+		}
+	}()
+	return j
+}
+
+// write adds the games to the playlists in memory and saves the playlists that
+// changed. The games already in the playlists get the metadata they lack. It
+// returns the number of games added.
+func write(games <-chan dat.Game) int {
+	added := 0
+	changed := map[string]bool{}
+	for game := range games {
+		if len(game.Description) == 0 {
+			continue
+		}
+		game = withMetadata(game)
+		CSVPath := filepath.Join(settings.Current.PlaylistsDirectory, game.System+".csv")
+		entry := playlists.Game{
+			Path:      game.Path,
+			Name:      game.Description,
+			CRC32:     uint32(game.ROMs[0].CRC),
+			Region:    playlists.Region(game.Name),
+			Genre:     game.Genre,
+			Year:      game.Year,
+			Developer: game.Developer,
+		}
+		if playlists.Add(CSVPath, entry) {
+			changed[CSVPath] = true
+			added++
+		} else if playlists.Update(CSVPath, entry) {
+			changed[CSVPath] = true
+		}
+	}
+	for path := range changed {
+		playlists.Save(path)
+	}
+	return added
+}
+
+// withMetadata completes a game taken from a scan index written before the
+// index kept the metadata, by looking it up in the database
+func withMetadata(game dat.Game) dat.Game {
+	crc := uint32(game.ROMs[0].CRC)
+	if game.Genre != "" || game.Year != "" || game.Developer != "" || crc == 0 {
+		return game
+	}
+	for _, g := range state.DB.ByCRC(crc) {
+		if g.System == game.System && g.Name == game.Name {
+			game.Genre, game.Year, game.Developer = g.Genre, g.Year, g.Developer
+			break
+		}
+	}
+	return game
+}
@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/libretro/ludo/dat"
+	"github.com/libretro/ludo/playlists"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/state"
+)
+
+// scanAll runs a scan and collects its results
+func scanAll(dir string, roms []string, cancel chan struct{}) ([]dat.Game, Progress) {
+	games := make(chan dat.Game)
+	progress := make(chan Progress, 1)
+	go Scan(dir, roms, games, progress, cancel)
+	last := make(chan Progress)
+	go func() {
+		var p Progress
+		for p = range progress {
+		}
+		last <- p
+	}()
+	var found []dat.Game
+	for game := range games {
+		found = append(found, game)
+	}
+	return found, <-last
+}
+
+func Test_Scan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg.DataHome = dir
+
+	var roms []string
+	var games []dat.Game
+	for i := 0; i < 20; i++ {
+		rom := filepath.Join(dir, fmt.Sprintf("game%d.sfc", i))
+		content := []byte(fmt.Sprintf("rom content %d", i))
+		ioutil.WriteFile(rom, content, 0644)
+		roms = append(roms, rom)
+		games = append(games, dat.Game{
+			Name:        fmt.Sprintf("Game %d", i),
+			Description: fmt.Sprintf("Game %d", i),
+			ROMs:        []dat.ROM{{Name: "game.sfc", CRC: dat.CRC(crc32.ChecksumIEEE(content))}},
+		})
+	}
+	state.DB = dat.NewDB(map[string]dat.Dat{"Nintendo - SNES": {Games: games}})
+	defer func() { state.DB = dat.DB{} }()
+
+	workers := Workers
+	Workers = 4
+	defer func() { Workers = workers }()
+
+	t.Run("Identifies the files in parallel", func(t *testing.T) {
+		found, p := scanAll(dir, roms, nil)
+		if len(found) != len(roms) {
+			t.Errorf("Scan() found %d games, want %d", len(found), len(roms))
+		}
+		if !p.Finished || p.Canceled || p.Done != len(roms) || p.Total != len(roms) {
+			t.Errorf("Scan() progress = %+v", p)
+		}
+	})
+
+	t.Run("Stops when canceled", func(t *testing.T) {
+		os.Remove(filepath.Join(xdg.DataHome, "ludo", "scanindex.json"))
+		cancel := make(chan struct{})
+		close(cancel)
+		found, p := scanAll(dir, roms, cancel)
+		if len(found) != 0 || !p.Finished || !p.Canceled || p.Done != 0 {
+			t.Errorf("Scan() = %v, %+v", found, p)
+		}
+	})
+}
+
+func Test_write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings.Current.PlaylistsDirectory = dir
+	defer func() { playlists.Playlists = map[string]playlists.Playlist{} }()
+
+	games := make(chan dat.Game, 4)
+	games <- dat.Game{Name: "B (Europe)", Description: "B", Path: "/roms/b.sfc", System: "SNES", ROMs: []dat.ROM{{CRC: 2}}}
+	games <- dat.Game{Name: "A (USA)", Description: "A", Path: "/roms/a.sfc", System: "SNES", ROMs: []dat.ROM{{CRC: 1}}}
+	games <- dat.Game{Name: "A (USA)", Description: "A", Path: "/roms/a.sfc", System: "SNES", ROMs: []dat.ROM{{CRC: 1}}}
+	games <- dat.Game{Name: "B (Europe)", Description: "B", Path: "/roms/b.sfc", System: "SNES", ROMs: []dat.ROM{{CRC: 2}}, Genre: "Puzzle", Year: "1990"}
+	close(games)
+
+	if added := write(games); added != 2 {
+		t.Errorf("write() = %v, want %v", added, 2)
+	}
+	csv := filepath.Join(dir, "SNES.csv")
+	data, _ := ioutil.ReadFile(csv)
+	want := "#ludo-playlist\t2\n" +
+		"/roms/a.sfc\tA\t1\t\tUSA\t\t\t\t\n" +
+		"/roms/b.sfc\tB\t2\t\tEurope\tPuzzle\t1990\t\t\n"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestProgress_String(t *testing.T) {
+	p := newProgress("/roms", 120, 500, 0, 2*time.Second)
+	if got, want := p.String(), "120/500 files, 60 files/s, 6s left"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+	p.Finished = true
+	if got, want := p.String(), "120/500 files"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func Test_start(t *testing.T) {
+	j, ok := start("/roms")
+	if !ok {
+		t.Fatal("start() didn't register a new job")
+	}
+	if finish(j) {
+		t.Errorf("finish() = true without a new request")
+	}
+
+	j, _ = start("/roms")
+	if again, ok := start("/roms"); ok || again != j {
+		t.Errorf("start() = %v, %v, want the running job", again, ok)
+	}
+	if !finish(j) {
+		t.Errorf("finish() = false after a new request")
+	}
+	if len(Running()) != 0 {
+		t.Errorf("Running() = %v", Running())
+	}
+}
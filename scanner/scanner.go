@@ -1,78 +1,141 @@
 // Package scanner generates game playlists by scanning your game collection
-// against the database. It uses CRC checksums for No-Intro zip files and
-// name matching for Redump cue files.
+// against the database. It uses CRC checksums for No-Intro zip files, with MD5
+// and SHA1 to tell apart games sharing a CRC. Disc images are identified by
+// the serial read from the disc, or by name matching for Redump cue files.
 package scanner
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
 	"hash/crc32"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/adrg/xdg"
 	"github.com/libretro/ludo/dat"
-	ntf "github.com/libretro/ludo/notifications"
-	"github.com/libretro/ludo/playlists"
-	"github.com/libretro/ludo/settings"
 	"github.com/libretro/ludo/state"
 	"github.com/libretro/ludo/utils"
 )
 
-// LoadDB loops over the RDBs in a given directory and parses them
+// datExts are the extensions of the databases LoadDB can parse
+var datExts = map[string]bool{".dat": true, ".xml": true, ".rdb": true}
+
+// parseDat parses a database with the parser matching its extension. Logiqx
+// and ClrMamePro dats share the .dat extension, they are told apart by their
+// content.
+func parseDat(path string) (dat.Dat, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return dat.Dat{}, err
+	}
+	switch filepath.Ext(path) {
+	case ".rdb":
+		return dat.ParseRDB(data)
+	case ".dat":
+		if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			return dat.ParseClrMamePro(data)
+		}
+	}
+	return dat.Parse(data), nil
+}
+
+// LoadDB walks a directory and its subdirectories, like a checkout of the
+// libretro database, and parses the databases found. The parsed databases and
+// their indexes are cached, they are only parsed again when a file changed.
+// The databases of the same system, like System.rdb and System.dat, are
+// merged.
 func LoadDB(dir string) (dat.DB, error) {
-	files, err := ioutil.ReadDir(dir)
+	if _, err := os.Stat(dir); err != nil {
+		return dat.DB{}, err
+	}
+	sources := dat.Sources{}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			log.Println(err)
+			return nil
+		}
+		if !fi.IsDir() && datExts[filepath.Ext(path)] {
+			sources[path] = dat.Source{Size: fi.Size(), ModTime: fi.ModTime()}
+		}
+		return nil
+	})
 	if err != nil {
 		return dat.DB{}, err
 	}
-	db := make(dat.DB)
-	for _, f := range files {
-		name := f.Name()
-		if !strings.Contains(name, ".dat") {
+
+	cachePath := filepath.Join(xdg.CacheHome, "ludo", "dat.gob")
+	if db, err := dat.ReadCache(cachePath, sources); err == nil {
+		db.Fingerprint = sources.Fingerprint()
+		return db, nil
+	}
+
+	// Parse in a stable order, so merged systems always list their games in
+	// the same order
+	var paths []string
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	parsed := map[string][]dat.Dat{}
+	for _, path := range paths {
+		d, err := parseDat(path)
+		if err != nil {
+			log.Println(path, err)
 			continue
 		}
-		system := name[0 : len(name)-4]
-		bytes, _ := ioutil.ReadFile(filepath.Join(dir, name))
-		db[system] = dat.Parse(bytes)
+		system := utils.FileName(path)
+		parsed[system] = append(parsed[system], d)
+	}
+	dats := map[string]dat.Dat{}
+	for system, ds := range parsed {
+		dats[system] = dat.Merge(ds...)
+	}
+	db := dat.NewDB(dats)
+	db.Fingerprint = sources.Fingerprint()
+	if err := db.WriteCache(cachePath, sources); err != nil {
+		log.Println(err)
 	}
 	return db, nil
 }
 
-// ScanDir scans a full directory, report progress and generate playlists
-func ScanDir(dir string, doneCb func()) {
-	n := ntf.DisplayAndLog(ntf.Info, "Menu", "Scanning %s", dir)
-	roms, err := utils.AllFilesIn(dir)
-	if err != nil {
-		n.Update(ntf.Error, err.Error())
-		return
+// hashes returns a function computing the MD5 and SHA1 of a ROM, so they are
+// only computed when needed
+func hashes(bytes []byte) func() (dat.Hashes, error) {
+	return func() (dat.Hashes, error) {
+		md5sum := md5.Sum(bytes)
+		sha1sum := sha1.Sum(bytes)
+		return dat.Hashes{
+			MD5:  hex.EncodeToString(md5sum[:]),
+			SHA1: hex.EncodeToString(sha1sum[:]),
+		}, nil
 	}
-	games := make(chan (dat.Game))
-	go Scan(dir, roms, games, n)
-	go func() {
-		i := 0
-		for game := range games {
-			os.MkdirAll(settings.Current.PlaylistsDirectory, os.ModePerm)
-			CSVPath := filepath.Join(settings.Current.PlaylistsDirectory, game.System+".csv")
-			if playlists.Contains(CSVPath, game.Path, uint32(game.ROMs[0].CRC)) {
-				continue
-			}
-			f, _ := os.OpenFile(CSVPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-			if len(game.Description) == 0 {
-				continue
-			}
-			f.WriteString(game.Path + "\t")
-			f.WriteString(game.Description + "\t")
-			if game.ROMs[0].CRC > 0 {
-				f.WriteString(strconv.FormatUint(uint64(game.ROMs[0].CRC), 16))
-			}
-			f.WriteString("\n")
-			f.Close()
-			i++
+}
+
+// zipHashes returns a function computing the MD5 and SHA1 of a ROM in a zip
+func zipHashes(rom *zip.File) func() (dat.Hashes, error) {
+	return func() (dat.Hashes, error) {
+		h, err := rom.Open()
+		if err != nil {
+			return dat.Hashes{}, err
 		}
-		doneCb()
-		n.Update(ntf.Success, "Done scanning. %d new games found.", i)
-	}()
+		defer h.Close()
+		bytes, err := ioutil.ReadAll(h)
+		if err != nil {
+			return dat.Hashes{}, err
+		}
+		return hashes(bytes)()
+	}
 }
 
 // Returns the checksum and headerless checksum of a ROM
@@ -100,55 +163,281 @@ var headerSizes = map[string]uint{
 	".lnx": 64,
 }
 
-// Scan scans a list of roms against the database
-func Scan(dir string, roms []string, games chan (dat.Game), n *ntf.Notification) {
-	for i, f := range roms {
-		ext := filepath.Ext(f)
-		switch ext {
-		case ".zip":
-			// Open the ZIP archive
-			z, err := zip.OpenReader(f)
-			if err != nil {
-				n.Update(ntf.Error, err.Error())
-				continue
-			}
-			for _, rom := range z.File {
-				romExt := filepath.Ext(rom.Name)
-				// these 4 systems might have headered or headerless roms and need special logic
-				if headerSize, ok := headerSizes[romExt]; ok {
-					crc, crcHeaderless, err := checksumHeaderless(rom, headerSize)
-					if err != nil {
-						n.Update(ntf.Error, err.Error())
-						continue
-					}
-					state.DB.FindByCRC(f, rom.Name, crc, games)
-					state.DB.FindByCRC(f, rom.Name, crcHeaderless, games)
-					n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
-				} else if rom.CRC32 > 0 {
-					// Look for a matching game entry in the database
-					state.DB.FindByCRC(f, rom.Name, rom.CRC32, games)
-					n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
+// romExts are the extensions of the ROMs identified by their checksum
+var romExts = map[string]bool{
+	".32x": true, "a52": true, ".a78": true, ".col": true, ".crt": true, ".d64": true,
+	".pce": true, ".fds": true, ".gb": true, ".gba": true, ".gbc": true, ".gen": true,
+	".gg": true, ".ipf": true, ".j64": true, ".jag": true, ".lnx": true, ".md": true,
+	".n64": true, ".nes": true, ".ngc": true, ".nds": true, ".rom": true, ".sfc": true,
+	".sg": true, ".smc": true, ".smd": true, ".sms": true, ".ws": true, ".wsc": true,
+}
+
+// scannable tells if the scanner knows how to identify a file
+func scannable(path string) bool {
+	switch ext := filepath.Ext(path); ext {
+	case ".zip", ".cue", ".iso":
+		return true
+	default:
+		return romExts[ext]
+	}
+}
+
+// recordHashes stores the hashes computed by hash in the index entry
+func recordHashes(e *IndexEntry, hash func() (dat.Hashes, error)) func() (dat.Hashes, error) {
+	return func() (dat.Hashes, error) {
+		h, err := hash()
+		if err == nil {
+			e.MD5, e.SHA1 = h.MD5, h.SHA1
+		}
+		return h, err
+	}
+}
+
+// identify looks for the games matching a file in the database, it fills the
+// checksums of the index entry along the way
+func identify(f string, e *IndexEntry, games chan (dat.Game)) error {
+	ext := filepath.Ext(f)
+	switch ext {
+	case ".zip":
+		// Open the ZIP archive
+		z, err := zip.OpenReader(f)
+		if err != nil {
+			return err
+		}
+		defer z.Close()
+		for _, rom := range z.File {
+			romExt := filepath.Ext(rom.Name)
+			// these 4 systems might have headered or headerless roms and need special logic
+			if headerSize, ok := headerSizes[romExt]; ok {
+				crc, crcHeaderless, err := checksumHeaderless(rom, headerSize)
+				if err != nil {
+					return err
 				}
+				e.CRC = crc
+				state.DB.FindByCRC(f, rom.Name, crc, games)
+				state.DB.FindByCRC(f, rom.Name, crcHeaderless, games)
+			} else if rom.CRC32 > 0 {
+				// Look for a matching game entry in the database
+				e.CRC = rom.CRC32
+				state.DB.FindByHash(f, rom.Name, rom.CRC32, recordHashes(e, zipHashes(rom)), games)
 			}
-			z.Close()
-		case ".cue":
-			// Look for a matching game entry in the database
+		}
+	case ".cue", ".iso":
+		// Look for a matching game entry in the database, by serial first
+		// as disc images are often renamed
+		found := 0
+		if serial, err := discSerial(f); err == nil {
+			e.Serial = serial
+			found = state.DB.FindBySerial(f, serial, games)
+		}
+		if found == 0 && ext == ".cue" {
 			state.DB.FindByROMName(f, filepath.Base(f), 0, games)
-			n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
-		case ".32x", "a52", ".a78", ".col", ".crt", ".d64", ".pce", ".fds", ".gb", ".gba", ".gbc", ".gen", ".gg", ".ipf", ".j64", ".jag", ".lnx", ".md", ".n64", ".nes", ".ngc", ".nds", ".rom", ".sfc", ".sg", ".smc", ".smd", ".sms", ".ws", ".wsc":
-			bytes, err := ioutil.ReadFile(f)
-			if err != nil {
-				n.Update(ntf.Error, err.Error())
-				continue
+		}
+	default:
+		bytes, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		e.CRC = crc32.ChecksumIEEE(bytes)
+		state.DB.FindByHash(f, utils.FileName(f), e.CRC, recordHashes(e, hashes(bytes)), games)
+		if headerSize, ok := headerSizes[ext]; ok {
+			crcHeaderless := crc32.ChecksumIEEE(bytes[headerSize:])
+			state.DB.FindByCRC(f, utils.FileName(f), crcHeaderless, games)
+		}
+	}
+	return nil
+}
+
+// scanFile identifies a file and returns its index entry
+func scanFile(f string) (IndexEntry, error) {
+	var e IndexEntry
+	err := match(&e, func(games chan dat.Game) error {
+		return identify(f, &e, games)
+	})
+	return e, err
+}
+
+// rematch matches a file against the database again, with the checksums and
+// serial of an index entry written with other databases. Zips and headered
+// ROMs are identified again, the checksums of the entry are not enough for
+// them, and reading the CRCs of a zip is cheap anyway.
+func rematch(f string, e IndexEntry) (IndexEntry, error) {
+	ext := filepath.Ext(f)
+	_, headered := headerSizes[ext]
+	disc := ext == ".cue" || ext == ".iso"
+	if ext == ".zip" || headered || (!disc && e.CRC == 0) {
+		fresh, err := scanFile(f)
+		fresh.Size, fresh.ModTime = e.Size, e.ModTime
+		return fresh, err
+	}
+	err := match(&e, func(games chan dat.Game) error {
+		if disc {
+			found := state.DB.FindBySerial(f, e.Serial, games)
+			if found == 0 && ext == ".cue" {
+				state.DB.FindByROMName(f, filepath.Base(f), 0, games)
+			}
+			return nil
+		}
+		state.DB.FindByHash(f, utils.FileName(f), e.CRC, cachedHashes(f, &e), games)
+		return nil
+	})
+	return e, err
+}
+
+// cachedHashes returns the MD5 and SHA1 of the index entry, they are only
+// computed if the entry doesn't have them yet
+func cachedHashes(f string, e *IndexEntry) func() (dat.Hashes, error) {
+	return func() (dat.Hashes, error) {
+		if e.MD5 != "" && e.SHA1 != "" {
+			return dat.Hashes{MD5: e.MD5, SHA1: e.SHA1}, nil
+		}
+		bytes, err := ioutil.ReadFile(f)
+		if err != nil {
+			return dat.Hashes{}, err
+		}
+		return recordHashes(e, hashes(bytes))()
+	}
+}
+
+// match collects the games sent by find in the index entry
+func match(e *IndexEntry, find func(games chan dat.Game) error) error {
+	found := make(chan (dat.Game))
+	done := make(chan ([]dat.Game))
+	go func() {
+		var games []dat.Game
+		for game := range found {
+			games = append(games, game)
+		}
+		done <- games
+	}()
+	err := find(found)
+	close(found)
+	e.Games = indexGames(<-done)
+	return err
+}
+
+// Workers is the number of files identified in parallel
+var Workers = runtime.NumCPU()
+
+// progressInterval is the delay between two progress reports
+var progressInterval = 250 * time.Millisecond
+
+// Scan scans a list of roms against the database with a pool of workers. The
+// files that didn't change since the previous scan are not read again, the
+// games they matched are taken from the index. Progress is reported regularly
+// to progress, and the scan stops early when cancel is closed. Both games and
+// progress are closed when the scan is over.
+func Scan(dir string, roms []string, games chan<- dat.Game, progress chan<- Progress, cancel <-chan struct{}) {
+	var files []string
+	for _, f := range roms {
+		if scannable(f) {
+			files = append(files, f)
+		}
+	}
+
+	idx := acquireIndex()
+	var done, failed int32
+	start := time.Now()
+	report := func() Progress {
+		return newProgress(dir, int(atomic.LoadInt32(&done)), len(files), int(atomic.LoadInt32(&failed)), time.Since(start))
+	}
+
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	wg.Add(Workers)
+	for i := 0; i < Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for f := range paths {
+				if err := scanIndexed(f, idx, &shared.Mutex, games); err != nil {
+					log.Println(err)
+					atomic.AddInt32(&failed, 1)
+				}
+				atomic.AddInt32(&done, 1)
 			}
-			crc := crc32.ChecksumIEEE(bytes)
-			state.DB.FindByCRC(f, utils.FileName(f), crc, games)
-			if headerSize, ok := headerSizes[ext]; ok {
-				crcHeaderless := crc32.ChecksumIEEE(bytes[headerSize:])
-				state.DB.FindByCRC(f, utils.FileName(f), crcHeaderless, games)
+		}()
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case progress <- report():
+				default: // the previous report wasn't read yet
+				}
+			case <-stop:
+				return
 			}
-			n.Update(ntf.Info, strconv.Itoa(i)+"/"+strconv.Itoa(len(roms))+" "+f)
+		}
+	}()
+
+	canceled := false
+feed:
+	for _, f := range files {
+		// Check the cancelation first, select picks randomly between the
+		// cases that are ready
+		select {
+		case <-cancel:
+			canceled = true
+			break feed
+		default:
+		}
+		select {
+		case paths <- f:
+		case <-cancel:
+			canceled = true
+			break feed
 		}
 	}
+	close(paths)
+	wg.Wait()
+	close(stop)
+
+	// Files that were not reached are not missing, only prune complete scans
+	if err := releaseIndex(dir, roms, !canceled); err != nil {
+		log.Println(err)
+	}
+
+	p := report()
+	p.Finished = true
+	p.Canceled = canceled
+	progress <- p
+	close(progress)
 	close(games)
 }
+
+// scanIndexed sends the games matched by a file, from the index if the file
+// didn't change, or by identifying it
+func scanIndexed(f string, idx Index, mutex *sync.Mutex, games chan<- dat.Game) error {
+	fi, err := os.Stat(f)
+	if err != nil {
+		return err
+	}
+	mutex.Lock()
+	e, ok, fresh := idx.Lookup(f, fi, state.DB.Fingerprint)
+	mutex.Unlock()
+	if !fresh {
+		if ok {
+			e, err = rematch(f, e)
+		} else {
+			e, err = scanFile(f)
+			e.Size = fi.Size()
+			e.ModTime = fi.ModTime()
+		}
+		if err != nil {
+			return err
+		}
+		e.DB = state.DB.Fingerprint
+		mutex.Lock()
+		idx[f] = e
+		mutex.Unlock()
+	}
+	for _, game := range e.games(f) {
+		games <- game
+	}
+	return nil
+}
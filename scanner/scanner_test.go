@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+func Test_LoadDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg.CacheHome = filepath.Join(dir, "cache")
+	dats := filepath.Join(dir, "dats")
+	os.Mkdir(dats, os.ModePerm)
+
+	ioutil.WriteFile(filepath.Join(dats, "Nintendo - SNES.dat"), []byte(`<?xml version="1.0"?>
+<datafile>
+	<game name="Game (USA)">
+		<description>Game (USA)</description>
+		<rom name="Game (USA).sfc" crc="1234abcd"/>
+	</game>
+</datafile>`), 0644)
+	ioutil.WriteFile(filepath.Join(dats, "Nintendo - SNES.xml"), []byte(`<datafile>
+	<game name="Game (Japan)">
+		<description>Game (Japan)</description>
+		<rom name="Game (Japan).sfc" crc="abcd1234"/>
+	</game>
+</datafile>`), 0644)
+	// Layout of the libretro database, metadata comes from other files
+	os.MkdirAll(filepath.Join(dats, "metadat", "genre"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dats, "metadat", "genre", "Nintendo - SNES.dat"), []byte(`game (
+	name "Game (USA)"
+	genre "Platform"
+	rom ( crc 1234ABCD )
+)
+game (
+	name "Unknown (USA)"
+	genre "Puzzle"
+	rom ( crc 99999999 )
+)`), 0644)
+	os.Mkdir(filepath.Join(dats, "dat"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dats, "dat", "Sony - PlayStation.dat"), []byte(`game (
+	name "Disc (USA)"
+	genre "Racing"
+	rom ( name "Disc (USA).cue" crc 00000001 )
+)`), 0644)
+	ioutil.WriteFile(filepath.Join(dats, "readme.txt"), []byte("not a dat"), 0644)
+
+	check := func(t *testing.T) {
+		db, err := LoadDB(dats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(db.Dats) != 2 || len(db.Dats["Nintendo - SNES"].Games) != 2 {
+			t.Errorf("LoadDB() = %+v", db.Dats)
+		}
+		if found := db.ByCRC(0x1234abcd); len(found) != 1 || found[0].Genre != "Platform" || found[0].ROMs[0].Name != "Game (USA).sfc" {
+			t.Errorf("ByCRC() = %+v", found)
+		}
+		if found := db.ByCRC(1); len(found) != 1 || found[0].Genre != "Racing" || found[0].System != "Sony - PlayStation" {
+			t.Errorf("ByCRC() = %+v", found)
+		}
+	}
+
+	t.Run("Parses the dats by format", check)
+
+	t.Run("Reads the cache", func(t *testing.T) {
+		if _, err := os.Stat(filepath.Join(xdg.CacheHome, "ludo", "dat.gob")); err != nil {
+			t.Fatal(err)
+		}
+		check(t)
+	})
+}
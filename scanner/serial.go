@@ -0,0 +1,208 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Disc images store 2048 bytes of data per sector. Raw images also keep the
+// sync pattern, header and error correction of each sector.
+const (
+	sectorSize    = 2048
+	rawSectorSize = 2352
+)
+
+var rawSync = []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+// disc reads the data sectors of a disc image, raw or not
+type disc struct {
+	f      *os.File
+	raw    bool
+	offset int64 // offset of the data in a raw sector
+}
+
+func openDisc(path string) (*disc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &disc{f: f}
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if bytes.Equal(header[:12], rawSync) {
+		d.raw = true
+		d.offset = 16 // Mode 1
+		if header[15] == 2 {
+			d.offset = 24 // Mode 2, skip the subheader
+		}
+	}
+	return d, nil
+}
+
+func (d *disc) Close() error {
+	return d.f.Close()
+}
+
+// sector reads the data of a sector
+func (d *disc) sector(lba uint32) ([]byte, error) {
+	pos := int64(lba) * sectorSize
+	if d.raw {
+		pos = int64(lba)*rawSectorSize + d.offset
+	}
+	b := make([]byte, sectorSize)
+	_, err := d.f.ReadAt(b, pos)
+	return b, err
+}
+
+// cueFirstTrack returns the path of the first file of a cue sheet
+func cueFirstTrack(cuePath string) (string, error) {
+	f, err := os.Open(cuePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(strings.ToUpper(line), "FILE ") {
+			continue
+		}
+		name := strings.TrimSpace(line[5:])
+		if strings.HasPrefix(name, `"`) {
+			name = name[1:]
+			if i := strings.Index(name, `"`); i >= 0 {
+				name = name[:i]
+			}
+		} else if i := strings.Index(name, " "); i >= 0 {
+			name = name[:i]
+		}
+		if filepath.IsAbs(name) {
+			return name, nil
+		}
+		return filepath.Join(filepath.Dir(cuePath), name), nil
+	}
+	return "", errors.New("no track in " + cuePath)
+}
+
+// discSerial reads the serial of a disc image, from a cue sheet or an ISO. It
+// supports PlayStation, Saturn and Sega CD discs.
+func discSerial(path string) (string, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".cue" {
+		track, err := cueFirstTrack(path)
+		if err != nil {
+			return "", err
+		}
+		path = track
+	}
+
+	d, err := openDisc(path)
+	if err != nil {
+		return "", err
+	}
+	defer d.Close()
+
+	first, err := d.sector(0)
+	if err != nil {
+		return "", err
+	}
+	if serial := segaSerial(first); serial != "" {
+		return serial, nil
+	}
+	return playstationSerial(d)
+}
+
+// segaSerial reads the product number from the header of Saturn and Sega CD
+// discs
+func segaSerial(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("SEGA SEGASATURN ")):
+		return strings.TrimSpace(string(header[0x20:0x2a]))
+	case bytes.HasPrefix(header, []byte("SEGADISCSYSTEM")):
+		// Like "GM MK-4407 -00", without the version
+		fields := strings.Fields(strings.TrimPrefix(string(header[0x180:0x18e]), "GM"))
+		if len(fields) == 0 {
+			return ""
+		}
+		return segaVersion.ReplaceAllString(fields[0], "")
+	}
+	return ""
+}
+
+var segaVersion = regexp.MustCompile(`-\d\d$`)
+
+// isoFile finds a file in the root directory of an ISO9660 file system, it
+// returns its first sector and size
+func isoFile(d *disc, name string) (uint32, uint32, error) {
+	pvd, err := d.sector(16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pvd[0] != 1 || string(pvd[1:6]) != "CD001" {
+		return 0, 0, errors.New("not an ISO9660 disc")
+	}
+	root := pvd[156:]
+	lba := binary.LittleEndian.Uint32(root[2:])
+	size := binary.LittleEndian.Uint32(root[10:])
+
+	for read := uint32(0); read < size; read += sectorSize {
+		dir, err := d.sector(lba + read/sectorSize)
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := 0; i < sectorSize; {
+			length := int(dir[i])
+			if length == 0 || i+length > sectorSize {
+				break // the records continue on the next sector
+			}
+			if length < 33 {
+				break // too short to have a name, the directory is corrupt
+			}
+			record := dir[i : i+length]
+			nameLength := int(record[32])
+			if 33+nameLength <= length {
+				recordName := strings.SplitN(string(record[33:33+nameLength]), ";", 2)[0]
+				if strings.EqualFold(recordName, name) {
+					return binary.LittleEndian.Uint32(record[2:]), binary.LittleEndian.Uint32(record[10:]), nil
+				}
+			}
+			i += length
+		}
+	}
+	return 0, 0, os.ErrNotExist
+}
+
+// systemCNFBoot matches the executable in SYSTEM.CNF, like
+// BOOT = cdrom:\SLUS_012.34;1
+var systemCNFBoot = regexp.MustCompile(`(?m)^\s*BOOT2?\s*=\s*cdrom0?:\\?(?:.*\\)?([^;\\\s]+)`)
+
+// playstationSerial reads the serial of a PlayStation disc from the name of
+// the executable listed in SYSTEM.CNF
+func playstationSerial(d *disc) (string, error) {
+	lba, size, err := isoFile(d, "SYSTEM.CNF")
+	if err != nil {
+		return "", err
+	}
+	cnf, err := d.sector(lba)
+	if err != nil {
+		return "", err
+	}
+	if size < sectorSize {
+		cnf = cnf[:size]
+	}
+	m := systemCNFBoot.FindSubmatch(cnf)
+	if m == nil {
+		return "", errors.New("no executable in SYSTEM.CNF")
+	}
+	return string(m[1]), nil
+}
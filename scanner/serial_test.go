@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isoImage builds a minimal ISO9660 image with a SYSTEM.CNF file
+func isoImage(cnf string) []byte {
+	sectors := make([][]byte, 20)
+	for i := range sectors {
+		sectors[i] = make([]byte, sectorSize)
+	}
+
+	pvd := sectors[16]
+	pvd[0] = 1
+	copy(pvd[1:], "CD001")
+	root := pvd[156:]
+	root[0] = 34
+	binary.LittleEndian.PutUint32(root[2:], 18)
+	binary.LittleEndian.PutUint32(root[10:], sectorSize)
+
+	dir := sectors[18]
+	offset := 0
+	for _, name := range []string{"\x00", "\x01", "README.TXT;1", "SYSTEM.CNF;1"} {
+		length := 33 + len(name)
+		if length%2 == 1 {
+			length++
+		}
+		dir[offset] = byte(length)
+		binary.LittleEndian.PutUint32(dir[offset+2:], 19)
+		binary.LittleEndian.PutUint32(dir[offset+10:], uint32(len(cnf)))
+		dir[offset+32] = byte(len(name))
+		copy(dir[offset+33:], name)
+		offset += length
+	}
+	copy(sectors[19], cnf)
+
+	var image []byte
+	for _, s := range sectors {
+		image = append(image, s...)
+	}
+	return image
+}
+
+// rawImage converts an image to raw Mode 2 sectors
+func rawImage(image []byte) []byte {
+	var raw []byte
+	for i := 0; i < len(image); i += sectorSize {
+		sector := make([]byte, rawSectorSize)
+		copy(sector, rawSync)
+		sector[15] = 2
+		copy(sector[24:], image[i:i+sectorSize])
+		raw = append(raw, sector...)
+	}
+	return raw
+}
+
+func Test_discSerial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ludo-serial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cnf := "BOOT = cdrom:\\SLUS_012.34;1\r\nTCB = 4\r\n"
+
+	saturn := make([]byte, sectorSize*2)
+	copy(saturn, "SEGA SEGASATURN SEGA ENTERPRISES")
+	copy(saturn[0x20:], "MK-81088  V1.000")
+
+	segacd := make([]byte, sectorSize*2)
+	copy(segacd, "SEGADISCSYSTEM  ")
+	copy(segacd[0x180:], "GM MK-4407 -00")
+
+	write("Game (Track 1).bin", rawImage(isoImage(cnf)))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"PlayStation ISO", write("ps1.iso", isoImage(cnf)), "SLUS_012.34"},
+		{"PlayStation cue", write("Renamed.cue", []byte("FILE \"Game (Track 1).bin\" BINARY\n  TRACK 01 MODE2/2352\n")), "SLUS_012.34"},
+		{"PlayStation 2 ISO", write("ps2.iso", isoImage("BOOT2 = cdrom0:\\SLES_500.03;1\n")), "SLES_500.03"},
+		{"Saturn", write("saturn.iso", saturn), "MK-81088"},
+		{"Sega CD", write("segacd.iso", segacd), "MK-4407"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discSerial(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("discSerial() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Corrupt directory record", func(t *testing.T) {
+		image := isoImage(cnf)
+		image[18*sectorSize] = 10
+		if _, err := discSerial(write("corrupt.iso", image)); err == nil {
+			t.Errorf("discSerial() should fail")
+		}
+	})
+
+	t.Run("Unknown disc", func(t *testing.T) {
+		if _, err := discSerial(write("blank.iso", make([]byte, sectorSize*20))); err == nil {
+			t.Errorf("discSerial() should fail")
+		}
+	})
+}
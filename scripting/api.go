@@ -0,0 +1,206 @@
+package scripting
+
+import (
+	"path/filepath"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	"github.com/libretro/ludo/memory"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/savestates"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/video"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// regions maps the memory region names used in scripts to libretro ids
+var regions = map[string]uint32{
+	"system": libretro.MemorySystemRAM,
+	"save":   libretro.MemorySaveRAM,
+	"video":  libretro.MemoryVideoRAM,
+	"rtc":    libretro.MemoryRTC,
+}
+
+var functions = map[string]lua.LGFunction{
+	"on_frame":    onFrame,
+	"frame":       getFrame,
+	"read":        read,
+	"write":       write,
+	"memory_size": memorySize,
+	"press":       press,
+	"release":     release,
+	"pressed":     pressed,
+	"draw_text":   drawText,
+	"save_state":  saveState,
+	"load_state":  loadState,
+	"log":         logMessage,
+}
+
+// loader allows scripts to require "ludo"
+func loader(L *lua.LState) int {
+	L.Push(newModule(L))
+	return 1
+}
+
+// newModule builds the table holding the API exposed to the scripts
+func newModule(L *lua.LState) *lua.LTable {
+	return L.SetFuncs(L.NewTable(), functions)
+}
+
+// ludo.on_frame(fn) registers a function called on each frame
+func onFrame(L *lua.LState) int {
+	hooks = append(hooks, L.CheckFunction(1))
+	return 0
+}
+
+// ludo.frame() returns the number of frames since the script was loaded
+func getFrame(L *lua.LState) int {
+	L.Push(lua.LNumber(frame))
+	return 1
+}
+
+// checkRegion reads the optional memory region name passed to memory functions
+func checkRegion(L *lua.LState, n int) uint32 {
+	name := L.OptString(n, "system")
+	id, ok := regions[name]
+	if !ok {
+		L.ArgError(n, "unknown memory region "+name)
+	}
+	return id
+}
+
+// ludo.read(address, [length], [region]) returns a byte, or a table of bytes
+// if a length is given
+func read(L *lua.LState) int {
+	addr := uint(L.CheckInt(1))
+	length := L.OptInt(2, 0)
+	id := checkRegion(L, 3)
+
+	n := uint(length)
+	if length == 0 {
+		n = 1
+	}
+	bytes, err := memory.Read(id, addr, n)
+	if err != nil {
+		L.RaiseError(err.Error())
+		return 0
+	}
+
+	if length == 0 {
+		L.Push(lua.LNumber(bytes[0]))
+		return 1
+	}
+
+	t := L.CreateTable(len(bytes), 0)
+	for _, b := range bytes {
+		t.Append(lua.LNumber(b))
+	}
+	L.Push(t)
+	return 1
+}
+
+// ludo.write(address, value, [region]) writes a byte, or a table of bytes
+func write(L *lua.LState) int {
+	addr := uint(L.CheckInt(1))
+	id := checkRegion(L, 3)
+
+	var bytes []byte
+	switch v := L.CheckAny(2).(type) {
+	case lua.LNumber:
+		bytes = []byte{byte(v)}
+	case *lua.LTable:
+		v.ForEach(func(_, b lua.LValue) {
+			bytes = append(bytes, byte(lua.LVAsNumber(b)))
+		})
+	default:
+		L.ArgError(2, "number or table expected")
+	}
+
+	if err := memory.Write(id, addr, bytes); err != nil {
+		L.RaiseError(err.Error())
+	}
+	return 0
+}
+
+// ludo.memory_size([region]) returns the size of a memory region
+func memorySize(L *lua.LState) int {
+	L.Push(lua.LNumber(memory.Size(checkRegion(L, 1))))
+	return 1
+}
+
+// checkButton reads the port and button name passed to input functions
+func checkButton(L *lua.LState) (int, uint32) {
+	port := L.CheckInt(1)
+	if port < 0 || port >= input.MaxPlayers {
+		L.ArgError(1, "invalid port")
+	}
+	name := L.CheckString(2)
+	id, ok := input.Buttons[name]
+	if !ok {
+		L.ArgError(2, "unknown button "+name)
+	}
+	return port, id
+}
+
+// ludo.press(port, button) holds a button during the current frame
+func press(L *lua.LState) int {
+	port, id := checkButton(L)
+	inputs[port][id] = 1
+	return 0
+}
+
+// ludo.release(port, button) releases a button during the current frame, even
+// if the player is pressing it
+func release(L *lua.LState) int {
+	port, id := checkButton(L)
+	inputs[port][id] = 0
+	return 0
+}
+
+// ludo.pressed(port, button) returns true if the player is pressing a button
+func pressed(L *lua.LState) int {
+	port, id := checkButton(L)
+	L.Push(lua.LBool(input.NewState[port][id] == 1))
+	return 1
+}
+
+// ludo.draw_text(x, y, text, [scale], [r, g, b, a]) draws a text on top of the
+// game during the current frame
+func drawText(L *lua.LState) int {
+	overlays = append(overlays, overlay{
+		x:     float32(L.CheckNumber(1)),
+		y:     float32(L.CheckNumber(2)),
+		text:  L.CheckString(3),
+		scale: float32(L.OptNumber(4, 0.5)),
+		color: video.Color{
+			R: float32(L.OptNumber(5, 1)),
+			G: float32(L.OptNumber(6, 1)),
+			B: float32(L.OptNumber(7, 1)),
+			A: float32(L.OptNumber(8, 1)),
+		},
+	})
+	return 0
+}
+
+// ludo.save_state(name) saves a state in the savestates directory
+func saveState(L *lua.LState) int {
+	if err := savestates.Save(L.CheckString(1)); err != nil {
+		L.RaiseError(err.Error())
+	}
+	return 0
+}
+
+// ludo.load_state(name) loads a state saved with ludo.save_state
+func loadState(L *lua.LState) int {
+	path := filepath.Join(settings.Current.SavestatesDirectory, L.CheckString(1)+".state")
+	if err := savestates.Load(path); err != nil {
+		L.RaiseError(err.Error())
+	}
+	return 0
+}
+
+// ludo.log(message) displays a notification
+func logMessage(L *lua.LState) int {
+	ntf.DisplayAndLog(ntf.Info, "Scripting", "%s", L.CheckString(1))
+	return 0
+}
@@ -0,0 +1,161 @@
+// Package scripting embeds a Lua interpreter to automate games. A script
+// placed in the scripts directory and named after the game is loaded along
+// with the game. Scripts can register per-frame hooks, read and write the core
+// memory, inject input, draw text overlays and save or load states. This is
+// useful for bots, training overlays and automated tests.
+package scripting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/libretro/ludo/input"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
+	"github.com/libretro/ludo/utils"
+	"github.com/libretro/ludo/video"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// overlay is a piece of text drawn on top of the game by a script
+type overlay struct {
+	x, y, scale float32
+	text        string
+	color       video.Color
+}
+
+var (
+	vm       *lua.LState                        // interpreter of the current script
+	hooks    []*lua.LFunction                   // per-frame hooks registered by the script
+	overlays []overlay                          // texts to draw during the current frame
+	inputs   [input.MaxPlayers]map[uint32]int16 // input injected during the current frame
+	frame    int                                // frames elapsed since the script was loaded
+)
+
+// Scripts are stopped when they run for too long, so a script stuck in a loop
+// can't freeze Ludo
+const (
+	loadTimeout  = time.Second
+	frameTimeout = 100 * time.Millisecond
+)
+
+// withTimeout runs f, a call to the interpreter L, and interrupts it after
+// timeout
+func withTimeout(L *lua.LState, timeout time.Duration, f func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+	return f()
+}
+
+// path returns the path of the script for a given game
+func path(gamePath string) string {
+	return filepath.Join(
+		settings.Current.ScriptsDirectory,
+		utils.FileName(gamePath)+".lua")
+}
+
+// Load looks for a script named after the game and runs it. It is not an error
+// if the game has no script.
+func Load(gamePath string) error {
+	Unload()
+
+	p := path(gamePath)
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return nil
+	}
+
+	vm = lua.NewState()
+	resetInputs()
+	vm.PreloadModule("ludo", loader)
+	vm.SetGlobal("ludo", newModule(vm))
+
+	L := vm
+	if err := withTimeout(L, loadTimeout, func() error { return L.DoFile(p) }); err != nil {
+		Unload()
+		return err
+	}
+
+	ntf.DisplayAndLog(ntf.Info, "Scripting", "Script loaded: %s", filepath.Base(p))
+	return nil
+}
+
+// Unload stops the current script and frees the interpreter
+func Unload() {
+	if vm != nil {
+		vm.Close()
+	}
+	vm = nil
+	hooks = nil
+	overlays = nil
+	frame = 0
+	for i := range inputs {
+		inputs[i] = nil
+	}
+}
+
+// resetInputs forgets the input injected during the previous frame
+func resetInputs() {
+	for i := range inputs {
+		inputs[i] = map[uint32]int16{}
+	}
+}
+
+// Running returns true if a script is loaded
+func Running() bool {
+	return vm != nil
+}
+
+// Update calls the frame hooks of the script. It is meant to be called once
+// per frame, after input.Poll and before running the core.
+func Update() {
+	if vm == nil {
+		return
+	}
+
+	frame++
+	overlays = nil
+	resetInputs()
+
+	L := vm
+	for _, hook := range hooks {
+		err := withTimeout(L, frameTimeout, func() error {
+			return L.CallByParam(lua.P{Fn: hook, NRet: 0, Protect: true})
+		})
+		if err != nil {
+			// Stop the script instead of reporting the same error every frame
+			ntf.DisplayAndLog(ntf.Error, "Scripting", "%s", err.Error())
+			Unload()
+			return
+		}
+	}
+
+	// Apply the input held by the script on top of the real input
+	for p := range inputs {
+		for id, v := range inputs[p] {
+			input.NewState[p][id] = v
+		}
+	}
+}
+
+// Render draws the overlays requested by the script during this frame
+func Render(vid *video.Video) {
+	if vm == nil || len(overlays) == 0 || vid.Font == nil {
+		return
+	}
+
+	fbw, fbh := vid.GetFramebufferSize()
+	vid.Font.UpdateResolution(fbw, fbh)
+	for _, o := range overlays {
+		vid.Font.SetColor(o.color)
+		vid.Font.Printf(o.x, o.y, o.scale, "%s", o.text)
+	}
+}
+
+// Frame returns the number of frames elapsed since the script was loaded
+func Frame() int {
+	return frame
+}
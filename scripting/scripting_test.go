@@ -0,0 +1,107 @@
+package scripting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libretro/ludo/input"
+	"github.com/libretro/ludo/libretro"
+	ntf "github.com/libretro/ludo/notifications"
+	"github.com/libretro/ludo/settings"
+)
+
+// load writes a script for a game and loads it, the returned function unloads
+// it
+func load(t *testing.T, script string) func() {
+	dir, err := ioutil.TempDir("", "ludo-scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Current.ScriptsDirectory = dir
+	if err := ioutil.WriteFile(filepath.Join(dir, "Game (USA).lua"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load("/roms/Game (USA).sfc"); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		Unload()
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_Update(t *testing.T) {
+	defer load(t, `
+local ludo = require("ludo")
+ludo.on_frame(function()
+	ludo.draw_text(10, 20, "100% done " .. ludo.frame())
+	ludo.press(0, "a")
+end)`)()
+
+	input.NewState = input.States{}
+	Update()
+	if len(overlays) != 1 || overlays[0].text != "100% done 1" {
+		t.Errorf("overlays = %+v", overlays)
+	}
+	if input.NewState[0][libretro.DeviceIDJoypadA] != 1 {
+		t.Errorf("the script didn't press A")
+	}
+	Update()
+	if Frame() != 2 || len(overlays) != 1 {
+		t.Errorf("Frame() = %d, overlays = %+v", Frame(), overlays)
+	}
+}
+
+func Test_Update_error(t *testing.T) {
+	ntf.Clear()
+	defer load(t, `
+local ludo = require("ludo")
+ludo.on_frame(function()
+	error("100%d broken")
+end)`)()
+
+	Update()
+	if Running() {
+		t.Errorf("Running() = true after an error")
+	}
+	list := ntf.List()
+	if len(list) == 0 || !strings.Contains(list[len(list)-1].Message, "100%d broken") {
+		t.Errorf("notifications = %+v", list)
+	}
+}
+
+func Test_timeout(t *testing.T) {
+	t.Run("Stops a script stuck on load", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ludo-scripts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		settings.Current.ScriptsDirectory = dir
+		ioutil.WriteFile(filepath.Join(dir, "Game (USA).lua"), []byte("while true do end"), 0644)
+		if err := Load("/roms/Game (USA).sfc"); err == nil || Running() {
+			t.Errorf("Load() = %v, want a timeout", err)
+		}
+	})
+
+	t.Run("Stops a hook stuck in a loop", func(t *testing.T) {
+		defer load(t, `
+local ludo = require("ludo")
+ludo.on_frame(function()
+	while true do end
+end)`)()
+
+		start := time.Now()
+		Update()
+		if Running() {
+			t.Errorf("Running() = true after a timeout")
+		}
+		if elapsed := time.Since(start); elapsed > 10*frameTimeout {
+			t.Errorf("Update() took %s", elapsed)
+		}
+	})
+}
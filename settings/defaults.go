@@ -8,13 +8,23 @@ import (
 
 func defaultSettings() Settings {
 	return Settings{
-		VideoFullscreen:   false,
-		VideoMonitorIndex: 0,
-		VideoFilter:       "Pixel Perfect",
-		MapAxisToDPad:     false,
-		AudioVolume:       0.5,
-		MenuAudioVolume:   0.25,
-		ShowHiddenFiles:   false,
+		VideoFullscreen:       false,
+		VideoMonitorIndex:     0,
+		VideoFilter:           "Pixel Perfect",
+		MapAxisToDPad:         false,
+		HotkeyButton:          "Off",
+		AudioVolume:           0.5,
+		MenuAudioVolume:       0.25,
+		ShowHiddenFiles:       false,
+		SavestatesCompression: true,
+		SoftpatchFullpath:     true,
+		AutoResume:            "Ask",
+		SRAMBackups:           5,
+		SyncBackend:           "Off",
+		ControlServerPort:     55400,
+		NetworkCommandsPort:   55355,
+		WatchDirectories:      true,
+		RegionPriority:        []string{"USA", "World", "Europe", "Japan"},
 		CoreForPlaylist: map[string]string{
 			"Atari - 2600":                                   "stella2014_libretro",
 			"Atari - 5200":                                   "atari800_libretro",
@@ -67,5 +77,8 @@ func defaultSettings() Settings {
 		SystemDirectory:      filepath.Join(xdg.DataHome, "ludo", "system"),
 		PlaylistsDirectory:   filepath.Join(xdg.DataHome, "ludo", "playlists"),
 		ThumbnailsDirectory:  filepath.Join(xdg.DataHome, "ludo", "thumbnails"),
+		ScriptsDirectory:     filepath.Join(xdg.DataHome, "ludo", "scripts"),
+		PatchesDirectory:     filepath.Join(xdg.DataHome, "ludo", "patches"),
+		PatchedDirectory:     filepath.Join(xdg.CacheHome, "ludo", "patched"),
 	}
 }
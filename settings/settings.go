@@ -32,9 +32,37 @@ type Settings struct {
 	MenuAudioVolume float32 `toml:"menu_audio_volume" label:"Menu Audio Volume" fmt:"%.1f" widget:"range"`
 	ShowHiddenFiles bool    `toml:"menu_showhiddenfiles" label:"Show Hidden Files" fmt:"%t" widget:"switch"`
 
-	MapAxisToDPad bool `toml:"input_map_axis_to_dpad" label:"Map Sticks To DPad" fmt:"%t" widget:"switch"`
+	MapAxisToDPad bool   `toml:"input_map_axis_to_dpad" label:"Map Sticks To DPad" fmt:"%t" widget:"switch"`
+	HotkeyButton  string `toml:"input_hotkey_button" label:"Hotkey Button" fmt:"<%s>"`
+
+	SavestatesCompression bool   `toml:"savestates_compression" label:"Compress Savestates" fmt:"%t" widget:"switch"`
+	SoftpatchFullpath     bool   `toml:"softpatch_fullpath" label:"Softpatch Full Path Cores" fmt:"%t" widget:"switch"`
+	AutoResume            string `toml:"auto_resume" label:"Auto Resume" fmt:"<%s>"`
+	SRAMBackups           int    `toml:"sram_backups" label:"SRAM Backups" fmt:"%d"`
+
+	SyncBackend  string `toml:"sync_backend" label:"Save Sync" fmt:"<%s>"`
+	SyncURL      string `toml:"sync_url" label:"Sync URL" fmt:"%s" widget:"text"`
+	SyncBucket   string `toml:"sync_bucket" label:"Sync S3 Bucket" fmt:"%s" widget:"text"`
+	SyncRegion   string `toml:"sync_region" label:"Sync S3 Region" fmt:"%s" widget:"text"`
+	SyncUsername string `toml:"sync_username" label:"Sync Username" fmt:"%s" widget:"text"`
+	SyncPassword string `toml:"sync_password" label:"Sync Password" fmt:"%s" widget:"password"`
+
+	ControlServer     bool `toml:"control_server" label:"Control Server" fmt:"%t" widget:"switch"`
+	ControlServerPort int  `hide:"always" toml:"control_server_port"`
+	// ControlServerToken authenticates the clients of the control server, it
+	// is generated when the server starts for the first time
+	ControlServerToken string `hide:"always" toml:"control_server_token"`
+
+	NetworkCommands     bool `toml:"network_commands" label:"Network Commands" fmt:"%t" widget:"switch"`
+	NetworkCommandsPort int  `hide:"always" toml:"network_commands_port"`
+
+	WatchDirectories   bool     `toml:"watch_directories" label:"Watch ROM Directories" fmt:"%t" widget:"switch"`
+	ScannedDirectories []string `hide:"always" toml:"scanned_directories"`
+
+	RegionPriority []string `hide:"always" toml:"region_priority"`
 
 	CoreForPlaylist map[string]string `hide:"always" toml:"core_for_playlist"`
+	Softpatches     map[string]string `hide:"always" toml:"softpatches"`
 
 	CoresDirectory       string `hide:"ludos" toml:"cores_dir" label:"Cores Directory" fmt:"%s" widget:"dir"`
 	AssetsDirectory      string `hide:"ludos" toml:"assets_dir" label:"Assets Directory" fmt:"%s" widget:"dir"`
@@ -45,6 +73,9 @@ type Settings struct {
 	SystemDirectory      string `hide:"ludos" toml:"system_dir" label:"System Directory" fmt:"%s" widget:"dir"`
 	PlaylistsDirectory   string `hide:"ludos" toml:"playlists_dir" label:"Playlists Directory" fmt:"%s" widget:"dir"`
 	ThumbnailsDirectory  string `hide:"ludos" toml:"thumbnail_dir" label:"Thumbnails Directory" fmt:"%s" widget:"dir"`
+	ScriptsDirectory     string `hide:"ludos" toml:"scripts_dir" label:"Scripts Directory" fmt:"%s" widget:"dir"`
+	PatchesDirectory     string `hide:"ludos" toml:"patches_dir" label:"Patches Directory" fmt:"%s" widget:"dir"`
+	PatchedDirectory     string `hide:"ludos" toml:"patched_dir" label:"Patched ROMs Directory" fmt:"%s" widget:"dir"`
 
 	SSHService       bool `hide:"app" toml:"ssh_service" label:"SSH" widget:"switch" service:"sshd.service" path:"/storage/.cache/services/sshd.conf"`
 	SambaService     bool `hide:"app" toml:"samba_service" label:"Samba" widget:"switch" service:"smbd.service" path:"/storage/.cache/services/samba.conf"`
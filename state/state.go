@@ -25,6 +25,13 @@ var CorePath string
 // GamePath is the path of the current game
 var GamePath string
 
+// GameCRC is the CRC32 checksum of the content loaded. For cores that load the
+// content from its path, it only covers the size and the beginning of the file.
+var GameCRC uint32
+
+// Patches are the paths of the softpatches applied to the current game
+var Patches []string
+
 // DB is the game database loaded on startup
 var DB dat.DB
 
@@ -31,8 +31,9 @@ func (video *Video) renderScreenshot() {
 
 // TakeScreenshot captures the ouput of video.Render and writes it to a file
 func (video *Video) TakeScreenshot(name string) error {
+	menuActive := state.MenuActive
 	state.MenuActive = false
-	defer func() { state.MenuActive = true }()
+	defer func() { state.MenuActive = menuActive }()
 
 	gl.UseProgram(video.defaultProgram)
 
@@ -0,0 +1,119 @@
+package watcher
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// inotify watches directories with the inotify API of Linux. Watches are not
+// recursive, so every subdirectory is watched too.
+type inotify struct {
+	fd      int
+	mutex   sync.Mutex
+	watches map[int32]string
+	closed  bool
+	notify  func(path string)
+}
+
+func newInotify(notify func(path string)) (backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	i := &inotify{fd: fd, watches: map[int32]string{}, notify: notify}
+	go i.read()
+	return i, nil
+}
+
+func (i *inotify) watch(dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(i.fd, path, inotifyMask)
+		if err != nil {
+			return err
+		}
+		i.mutex.Lock()
+		i.watches[int32(wd)] = path
+		i.mutex.Unlock()
+		return nil
+	})
+}
+
+// read handles the events until the backend is closed
+func (i *inotify) read() {
+	fd := i.fd
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(fd, buf)
+		i.mutex.Lock()
+		closed := i.closed
+		i.mutex.Unlock()
+		if closed {
+			i.mutex.Lock()
+			if i.fd >= 0 {
+				syscall.Close(i.fd)
+				i.fd = -1
+			}
+			i.mutex.Unlock()
+			return
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			i.mutex.Lock()
+			dir, ok := i.watches[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(i.watches, event.Wd)
+			}
+			i.mutex.Unlock()
+			if !ok {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			// Watch the directories created or moved into a watched one
+			if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				i.watch(path)
+			}
+			if event.Mask&inotifyMask != 0 {
+				i.notify(path)
+			}
+		}
+	}
+}
+
+// close removes the watches, the pending read then returns and the file
+// descriptor is closed
+func (i *inotify) close() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.closed = true
+	if len(i.watches) == 0 {
+		syscall.Close(i.fd)
+		i.fd = -1
+		return
+	}
+	for wd := range i.watches {
+		syscall.InotifyRmWatch(i.fd, uint32(wd))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package watcher
+
+import "errors"
+
+func newInotify(notify func(path string)) (backend, error) {
+	return nil, errors.New("inotify is only available on Linux")
+}
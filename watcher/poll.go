@@ -0,0 +1,100 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileState is what the poller compares between two walks
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// poller walks the watched directories periodically and reports the files
+// that appeared, vanished or changed
+type poller struct {
+	mutex     sync.Mutex
+	snapshots map[string]map[string]fileState
+	notify    func(path string)
+	done      chan struct{}
+}
+
+func newPoller(interval time.Duration, notify func(path string)) *poller {
+	p := &poller{
+		snapshots: map[string]map[string]fileState{},
+		notify:    notify,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.poll()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// snapshot lists the files of a directory and its subdirectories
+func snapshot(dir string) map[string]fileState {
+	files := map[string]fileState{}
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			files[path] = fileState{fi.Size(), fi.ModTime()}
+		}
+		return nil
+	})
+	return files
+}
+
+func (p *poller) watch(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	files := snapshot(dir)
+	p.mutex.Lock()
+	p.snapshots[dir] = files
+	p.mutex.Unlock()
+	return nil
+}
+
+// poll compares the directories with their previous snapshot
+func (p *poller) poll() {
+	p.mutex.Lock()
+	dirs := []string{}
+	for dir := range p.snapshots {
+		dirs = append(dirs, dir)
+	}
+	p.mutex.Unlock()
+
+	for _, dir := range dirs {
+		files := snapshot(dir)
+		p.mutex.Lock()
+		previous := p.snapshots[dir]
+		p.snapshots[dir] = files
+		p.mutex.Unlock()
+
+		for path, state := range files {
+			if old, ok := previous[path]; !ok || old != state {
+				p.notify(path)
+			}
+		}
+		for path := range previous {
+			if _, ok := files[path]; !ok {
+				p.notify(path)
+			}
+		}
+	}
+}
+
+func (p *poller) close() {
+	close(p.done)
+}
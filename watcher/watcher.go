@@ -0,0 +1,159 @@
+// Package watcher watches the directories that have been scanned, so games
+// copied to or deleted from them are added to or removed from the playlists
+// without scanning by hand. It relies on inotify on Linux and polls the
+// directories elsewhere, or when inotify is not available.
+package watcher
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libretro/ludo/settings"
+)
+
+// backend notifies the paths that changed in the watched directories
+type backend interface {
+	watch(dir string) error
+	close()
+}
+
+// Delays of the watcher. Copying files triggers many events, a directory is
+// only reported once it has been quiet for a while.
+var (
+	quietDelay   = 3 * time.Second
+	pollInterval = 30 * time.Second
+)
+
+var (
+	mutex    sync.Mutex
+	primary  backend
+	fallback backend
+	timers   = map[string]*time.Timer{}
+	onChange func(dir string)
+
+	// dirs is the watcher's copy of the scanned directories, the settings
+	// are only modified on the main thread
+	dirs []string
+)
+
+// Init sets the function called when the content of a scanned directory
+// changed. It is called from another goroutine.
+func Init(cb func(dir string)) {
+	onChange = cb
+}
+
+// Start watches the scanned directories
+func Start() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if primary != nil {
+		return nil
+	}
+	var err error
+	primary, err = newInotify(changed)
+	if err != nil {
+		log.Println("[Watcher]: inotify unavailable, polling:", err)
+		primary = newPoller(pollInterval, changed)
+	}
+	dirs = append([]string{}, settings.Current.ScannedDirectories...)
+	for _, dir := range dirs {
+		watch(dir)
+	}
+	return nil
+}
+
+// Stop stops watching the directories
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if primary != nil {
+		primary.close()
+		primary = nil
+	}
+	if fallback != nil {
+		fallback.close()
+		fallback = nil
+	}
+	for dir, t := range timers {
+		t.Stop()
+		delete(timers, dir)
+	}
+}
+
+// watch watches a directory, polling it if the primary backend fails, for
+// example when the inotify watches limit is reached
+func watch(dir string) {
+	err := primary.watch(dir)
+	if err == nil {
+		return
+	}
+	log.Println("[Watcher]: polling", dir, "instead:", err)
+	if fallback == nil {
+		fallback = newPoller(pollInterval, changed)
+	}
+	if err := fallback.watch(dir); err != nil {
+		log.Println("[Watcher]:", err)
+	}
+}
+
+// Add remembers a scanned directory and watches it
+func Add(dir string) error {
+	dir = filepath.Clean(dir)
+	for _, d := range settings.Current.ScannedDirectories {
+		if d == dir {
+			return nil
+		}
+	}
+	settings.Current.ScannedDirectories = append(settings.Current.ScannedDirectories, dir)
+
+	mutex.Lock()
+	dirs = append(dirs, dir)
+	if primary != nil {
+		watch(dir)
+	}
+	mutex.Unlock()
+
+	return settings.Save()
+}
+
+// root returns the scanned directory containing a path. It must be called
+// with the mutex held.
+func root(path string) (string, bool) {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// changed is called by the backends when a path changed. The scanned
+// directory is reported after a quiet delay.
+func changed(path string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	dir, ok := root(path)
+	if !ok {
+		return
+	}
+
+	if t, ok := timers[dir]; ok {
+		t.Reset(quietDelay)
+		return
+	}
+	timers[dir] = time.AfterFunc(quietDelay, func() {
+		mutex.Lock()
+		delete(timers, dir)
+		mutex.Unlock()
+		if onChange != nil {
+			onChange(dir)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the paths notified by a backend
+type recorder struct {
+	mutex sync.Mutex
+	paths []string
+}
+
+func (r *recorder) notify(path string) {
+	r.mutex.Lock()
+	r.paths = append(r.paths, path)
+	r.mutex.Unlock()
+}
+
+func (r *recorder) has(path string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, p := range r.paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// eventually waits for a condition to be true
+func eventually(t *testing.T, cond func() bool) {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met in time")
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ludo-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func Test_poller(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	old := filepath.Join(dir, "old.zip")
+	ioutil.WriteFile(old, []byte("old"), 0644)
+
+	r := &recorder{}
+	p := newPoller(10*time.Millisecond, r.notify)
+	defer p.close()
+	if err := p.watch(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	added := filepath.Join(dir, "sub", "new.zip")
+	os.Mkdir(filepath.Dir(added), os.ModePerm)
+	ioutil.WriteFile(added, []byte("new"), 0644)
+	os.Remove(old)
+
+	eventually(t, func() bool { return r.has(added) && r.has(old) })
+}
+
+func Test_inotify(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	r := &recorder{}
+	b, err := newInotify(r.notify)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer b.close()
+	if err := b.watch(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of new subdirectories are reported too
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, os.ModePerm)
+	eventually(t, func() bool { return r.has(sub) })
+	added := filepath.Join(sub, "new.zip")
+	ioutil.WriteFile(added, []byte("new"), 0644)
+
+	eventually(t, func() bool { return r.has(added) })
+}
+
+func Test_changed(t *testing.T) {
+	quietDelay = 50 * time.Millisecond
+	dirs = []string{"/roms"}
+	defer func() { dirs = nil }()
+
+	r := &recorder{}
+	Init(r.notify)
+	defer Init(nil)
+
+	changed("/roms/a.zip")
+	changed("/roms/snes/b.zip")
+	changed("/other/c.zip")
+
+	eventually(t, func() bool { return r.has("/roms") })
+	time.Sleep(2 * quietDelay)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.paths) != 1 {
+		t.Errorf("onChange called with %v, want [/roms]", r.paths)
+	}
+}